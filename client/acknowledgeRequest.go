@@ -60,7 +60,8 @@ func (s *AcknowledgeRequestService) Create(ctx *context.Context, acknowledgeRequ
 	return nil
 }
 
-// Prepare store request log to this services before starting run in transaction
+// Prepare store request log to this services before starting run in transaction,
+// the secret headers of the request are redacted by the default redaction policy
 func (s *AcknowledgeRequestService) Prepare(ctx *context.Context) error {
 	// write request log to this service
 	clientID, clientType := determineClient(ctx)
@@ -101,7 +102,7 @@ func (s *AcknowledgeRequestService) Prepare(ctx *context.Context) error {
 		ClientType:     clientType,
 		Method:         methodName,
 		URL:            urlPath,
-		Header:         appcontext.RequestHeader(ctx),
+		Header:         newRedactor(nil, nil).HeaderString(appcontext.RequestHeader(ctx)),
 		Request:        requestRaw,
 		Status:         "called",
 		HTTPStatusCode: 200,
@@ -138,14 +139,25 @@ func (s *AcknowledgeRequestService) Acknowledge(ctx *context.Context, status str
 
 	for _, clientRequest := range clientRequests {
 		// acknowledge client to commit / rollback
-		responseError := clientRequest.Client.CallClientWithBaseURLGiven(
-			ctx,
-			fmt.Sprintf("%s?s=%s", clientRequest.Request.URL, status),
-			Method(clientRequest.Request.Method),
-			clientRequest.Request.Request,
-			nil,
-			false,
-		)
+		acknowledgeURL := fmt.Sprintf("%s?s=%s", clientRequest.Request.URL, status)
+		var responseError *ResponseError
+		if clientRequest.Body != nil {
+			responseError = clientRequest.Client.Call(ctx, Method(clientRequest.Request.Method), "",
+				WithURL(acknowledgeURL),
+				WithRawBody(clientRequest.Body),
+				WithHeader("Content-Type", clientRequest.ContentType),
+				WithoutLog(),
+			)
+		} else {
+			responseError = clientRequest.Client.CallClientWithBaseURLGiven(
+				ctx,
+				acknowledgeURL,
+				Method(clientRequest.Request.Method),
+				clientRequest.Request.Request,
+				nil,
+				false,
+			)
+		}
 
 		if responseError != nil && responseError.Error != nil {
 			return responseError.Error
@@ -155,7 +167,7 @@ func (s *AcknowledgeRequestService) Acknowledge(ctx *context.Context, status str
 		_ = s.Create(&backgroundContext, &AcknowledgeRequest{
 			RequestID:          clientRequest.Request.ID,
 			CommitStatus:       status,
-			ReservedHolder:     clientRequest.Request.Request,
+			ReservedHolder:     clientRequest.Client.redactor().Metadata(clientRequest.Request.Request),
 			ReservedHolderName: reflect.TypeOf(clientRequest.Request.Request).Elem().Name(),
 			Message:            message,
 		})
@@ -0,0 +1,326 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+)
+
+// Errors of the circuit breaker, they're surfaced as the Error of the ResponseError
+var (
+	ErrCircuitOpen        = errors.New("circuit breaker is open")
+	ErrBreakerTimeout     = errors.New("circuit breaker timeout")
+	ErrBreakerConcurrency = errors.New("circuit breaker max concurrency reached")
+)
+
+// Enum value for circuit breaker state
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// Default values of the circuit breaker settings
+const (
+	defaultBreakerTimeout                = 5000 * time.Millisecond
+	defaultBreakerMaxConcurrentRequests  = 100
+	defaultBreakerErrorPercentThreshold  = 20
+	defaultBreakerRequestVolumeThreshold = 20
+	defaultBreakerSleepWindow            = 5000 * time.Millisecond
+	breakerRollingWindow                 = 10 * time.Second
+)
+
+// BreakerSettings represents the settings of a circuit breaker, the zero values use the defaults
+type BreakerSettings struct {
+	Timeout                time.Duration
+	MaxConcurrentRequests  int
+	ErrorPercentThreshold  int
+	RequestVolumeThreshold int
+	SleepWindow            time.Duration
+}
+
+// BreakerConfig represents the circuit breaker config of a client.
+// Endpoints are the settings of the paths starting with its key, each of them has its own breaker.
+// Native uses the in-process half-open breaker instead of hystrix-go.
+type BreakerConfig struct {
+	BreakerSettings
+	Endpoints map[string]BreakerSettings
+	Native    bool
+}
+
+// BreakerState represents the state of a circuit breaker.
+// The hystrix-go breaker only reports closed or open, its half-open state & counts aren't exposed by hystrix-go,
+// Requests & Failures are only counted by the native breaker.
+type BreakerState struct {
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	Native   bool   `json:"native"`
+	Requests int    `json:"requests"`
+	Failures int    `json:"failures"`
+}
+
+// circuitBreaker runs the call guarded by a circuit breaker
+type circuitBreaker interface {
+	Do(run func() error) error
+	State() *BreakerState
+}
+
+// breakers is the registry of the circuit breakers by its name & settings,
+// the clients sharing the name with the different settings don't share the breaker
+var breakers sync.Map
+
+// breakerKey returns the registry key of the breaker, it's the hystrix command name too
+func breakerKey(name string, settings BreakerSettings, isNative bool) string {
+	return fmt.Sprintf("%s|%d|%d|%d|%d|%d|%t", name, settings.Timeout, settings.MaxConcurrentRequests,
+		settings.ErrorPercentThreshold, settings.RequestVolumeThreshold, settings.SleepWindow, isNative)
+}
+
+func (s BreakerSettings) withDefaults() BreakerSettings {
+	if s.Timeout <= 0 {
+		s.Timeout = defaultBreakerTimeout
+	}
+	if s.MaxConcurrentRequests <= 0 {
+		s.MaxConcurrentRequests = defaultBreakerMaxConcurrentRequests
+	}
+	if s.ErrorPercentThreshold <= 0 {
+		s.ErrorPercentThreshold = defaultBreakerErrorPercentThreshold
+	}
+	if s.RequestVolumeThreshold <= 0 {
+		s.RequestVolumeThreshold = defaultBreakerRequestVolumeThreshold
+	}
+	if s.SleepWindow <= 0 {
+		s.SleepWindow = defaultBreakerSleepWindow
+	}
+	return s
+}
+
+type hystrixBreaker struct {
+	name    string
+	command string
+}
+
+func newHystrixBreaker(name string, command string, settings BreakerSettings) *hystrixBreaker {
+	hystrix.ConfigureCommand(command, hystrix.CommandConfig{
+		Timeout:                int(settings.Timeout / time.Millisecond),
+		MaxConcurrentRequests:  settings.MaxConcurrentRequests,
+		ErrorPercentThreshold:  settings.ErrorPercentThreshold,
+		RequestVolumeThreshold: settings.RequestVolumeThreshold,
+		SleepWindow:            int(settings.SleepWindow / time.Millisecond),
+	})
+	return &hystrixBreaker{name: name, command: command}
+}
+
+func (b *hystrixBreaker) Do(run func() error) error {
+	err := hystrix.Do(b.command, run, nil)
+	switch err {
+	case hystrix.ErrCircuitOpen:
+		return ErrCircuitOpen
+	case hystrix.ErrTimeout:
+		return ErrBreakerTimeout
+	case hystrix.ErrMaxConcurrency:
+		return ErrBreakerConcurrency
+	}
+	return err
+}
+
+// State returns closed or open only, hystrix-go doesn't expose the half-open state & the counts of the circuit
+func (b *hystrixBreaker) State() *BreakerState {
+	state := &BreakerState{Name: b.name, State: BreakerClosed}
+	circuit, _, err := hystrix.GetCircuit(b.command)
+	if err == nil && circuit.IsOpen() {
+		state.State = BreakerOpen
+	}
+	return state
+}
+
+// nativeBreaker is the in-process circuit breaker, it's opened when the error percentage of the rolling window
+// reaches the threshold and lets a single trial request through after the sleep window
+type nativeBreaker struct {
+	mu        sync.Mutex
+	name      string
+	settings  BreakerSettings
+	state     string
+	requests  int
+	failures  int
+	windowAt  time.Time
+	openedAt  time.Time
+	isTrying  bool
+	semaphore chan struct{}
+}
+
+func newNativeBreaker(name string, settings BreakerSettings) *nativeBreaker {
+	return &nativeBreaker{
+		name:      name,
+		settings:  settings,
+		state:     BreakerClosed,
+		windowAt:  time.Now(),
+		semaphore: make(chan struct{}, settings.MaxConcurrentRequests),
+	}
+}
+
+// allow returns whether the request is allowed and whether it's the trial request of the half-open state
+func (b *nativeBreaker) allow() (bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.settings.SleepWindow {
+			return false, false
+		}
+		b.state = BreakerHalfOpen
+		b.isTrying = true
+		return true, true
+	case BreakerHalfOpen:
+		if b.isTrying {
+			return false, false
+		}
+		b.isTrying = true
+		return true, true
+	}
+
+	if time.Since(b.windowAt) > breakerRollingWindow {
+		b.requests, b.failures, b.windowAt = 0, 0, time.Now()
+	}
+	return true, false
+}
+
+func (b *nativeBreaker) record(isTrial bool, isFailed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if isTrial {
+		b.isTrying = false
+		b.requests, b.failures, b.windowAt = 0, 0, time.Now()
+		if isFailed {
+			b.state, b.openedAt = BreakerOpen, time.Now()
+		} else {
+			b.state = BreakerClosed
+		}
+		return
+	}
+
+	b.requests++
+	if isFailed {
+		b.failures++
+	}
+	if b.state == BreakerClosed && b.requests >= b.settings.RequestVolumeThreshold &&
+		b.failures*100 >= b.settings.ErrorPercentThreshold*b.requests {
+		b.state, b.openedAt = BreakerOpen, time.Now()
+	}
+}
+
+func (b *nativeBreaker) Do(run func() error) error {
+	isAllowed, isTrial := b.allow()
+	if !isAllowed {
+		return ErrCircuitOpen
+	}
+
+	select {
+	case b.semaphore <- struct{}{}:
+	default:
+		b.record(isTrial, true)
+		return ErrBreakerConcurrency
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-b.semaphore }()
+		done <- run()
+	}()
+
+	timer := time.NewTimer(b.settings.Timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		b.record(isTrial, err != nil)
+		return err
+	case <-timer.C:
+		b.record(isTrial, true)
+		return ErrBreakerTimeout
+	}
+}
+
+func (b *nativeBreaker) State() *BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state
+	if state == BreakerOpen && time.Since(b.openedAt) >= b.settings.SleepWindow {
+		state = BreakerHalfOpen
+	}
+	return &BreakerState{
+		Name:     b.name,
+		State:    state,
+		Native:   true,
+		Requests: b.requests,
+		Failures: b.failures,
+	}
+}
+
+// breaker returns the circuit breaker of the path, it's created once by its name & settings
+func (c *HTTPClient) breaker(path string) circuitBreaker {
+	config := BreakerConfig{}
+	if c.BreakerConfig != nil {
+		config = *c.BreakerConfig
+	}
+
+	name := c.ClientName
+	settings := config.BreakerSettings
+	endpoint := ""
+	for prefix := range config.Endpoints {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(endpoint) {
+			endpoint = prefix
+		}
+	}
+	if endpoint != "" {
+		name = name + ":" + endpoint
+		settings = config.Endpoints[endpoint]
+	}
+
+	settings = settings.withDefaults()
+	key := breakerKey(name, settings, config.Native)
+	if existing, ok := breakers.Load(key); ok {
+		return existing.(circuitBreaker)
+	}
+
+	var cb circuitBreaker
+	if config.Native {
+		cb = newNativeBreaker(name, settings)
+	} else {
+		cb = newHystrixBreaker(name, key, settings)
+	}
+	existing, _ := breakers.LoadOrStore(key, cb)
+	return existing.(circuitBreaker)
+}
+
+// BreakerStates returns the states of the circuit breakers of the client
+func (c *HTTPClient) BreakerStates() []*BreakerState {
+	states := []*BreakerState{}
+	for _, state := range ListBreakerStates() {
+		if state.Name == c.ClientName || strings.HasPrefix(state.Name, c.ClientName+":") {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+// ListBreakerStates returns the states of all of the circuit breakers sorted by its name
+func ListBreakerStates() []*BreakerState {
+	states := []*BreakerState{}
+	breakers.Range(func(key, value interface{}) bool {
+		states = append(states, value.(circuitBreaker).State())
+		return true
+	})
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
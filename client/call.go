@@ -0,0 +1,854 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	"moul.io/http2curl"
+
+	"github.com/payfazz/commerce-kit/appcontext"
+	"github.com/payfazz/commerce-kit/types"
+)
+
+// CallRequest represents a single call flowing through the call middlewares
+type CallRequest struct {
+	Method        Method
+	Path          string
+	URL           string
+	Header        http.Header
+	Body          []byte
+	Request       interface{}
+	RequestRaw    types.Metadata
+	Log           *ClientRequestLog
+	Attempts      []*ClientRequestAttempt
+	RateLimitWait time.Duration
+	// Response is the response of the streamed call, its body is left open
+	Response  *http.Response
+	options   *callOptions
+	updateLog func()
+	// beforeAttempt is called before every attempt of the call, its error stops the call
+	beforeAttempt func() *ResponseError
+}
+
+// CallHandler executes the call and returns the response body
+type CallHandler func(ctx *context.Context, call *CallRequest) (string, *ResponseError)
+
+// CallMiddleware wraps the next handler of the call pipeline
+type CallMiddleware func(next CallHandler) CallHandler
+
+// CallOption configures a call of HTTPClient.Call
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	request             interface{}
+	rawBody             []byte
+	isRawBody           bool
+	result              interface{}
+	baseURL             string
+	fullURL             string
+	queryParams         interface{}
+	header              http.Header
+	isAcknowledgeNeeded bool
+	isWithoutLog        bool
+	isLogAllMethods     bool
+	errorDecoder        ErrorDecoder
+	isCaching           bool
+	isRedisCaching      bool
+	redisCacheDuration  time.Duration
+	redisCacheKeyPath   string
+	isCircuitBreaker    bool
+	maxNetworkRetries   *int
+	retryPolicy         *RetryPolicy
+	bodyEncoder         BodyEncoder
+	responseDecoder     ResponseDecoder
+	isStream            bool
+	maxSize             int64
+	newChecksumHash     func() hash.Hash
+	checksum            string
+	progress            ProgressFunc
+	middlewares         []CallMiddleware
+}
+
+func newCallOptions(opts []CallOption) *callOptions {
+	options := &callOptions{header: http.Header{}}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// CallSpec represents the request related values set by the call options, it's used by the fakes of Caller
+type CallSpec struct {
+	Request             interface{}
+	RawBody             []byte
+	Result              interface{}
+	BaseURL             string
+	URL                 string
+	QueryParams         interface{}
+	Header              http.Header
+	IsAcknowledgeNeeded bool
+	ResponseDecoder     ResponseDecoder
+}
+
+// NewCallSpec returns the values set by the call options
+func NewCallSpec(opts ...CallOption) *CallSpec {
+	options := newCallOptions(opts)
+	return &CallSpec{
+		Request:             options.request,
+		RawBody:             options.rawBody,
+		Result:              options.result,
+		BaseURL:             options.baseURL,
+		URL:                 options.fullURL,
+		QueryParams:         options.queryParams,
+		Header:              options.header,
+		IsAcknowledgeNeeded: options.isAcknowledgeNeeded,
+		ResponseDecoder:     options.decoder(),
+	}
+}
+
+// encoder returns the body encoder of the call, the request is sent as json by default
+func (o *callOptions) encoder() BodyEncoder {
+	if o.bodyEncoder != nil {
+		return o.bodyEncoder
+	}
+	return JSONEncoder
+}
+
+// decoder returns the response decoder of the call, the response is parsed as json by default
+func (o *callOptions) decoder() ResponseDecoder {
+	if o.responseDecoder != nil {
+		return o.responseDecoder
+	}
+	return JSONDecoder
+}
+
+// WithRequest sets the request to be sent as the body encoded by the body encoder, json by default
+func WithRequest(request interface{}) CallOption {
+	return func(o *callOptions) {
+		o.request = request
+	}
+}
+
+// WithRawBody sets the raw body to be sent as it is, its content type is json unless the Content-Type header is given
+func WithRawBody(body []byte) CallOption {
+	return func(o *callOptions) {
+		o.rawBody = body
+		o.isRawBody = true
+	}
+}
+
+// WithResult sets the pointer the response is parsed into by the response decoder, json by default
+func WithResult(result interface{}) CallOption {
+	return func(o *callOptions) {
+		o.result = result
+	}
+}
+
+// WithBodyEncoder encodes the request of the call by the encoder
+func WithBodyEncoder(encoder BodyEncoder) CallOption {
+	return func(o *callOptions) {
+		o.bodyEncoder = encoder
+	}
+}
+
+// WithResponseDecoder parses the response of the call into the result by the decoder
+func WithResponseDecoder(decoder ResponseDecoder) CallOption {
+	return func(o *callOptions) {
+		o.responseDecoder = decoder
+	}
+}
+
+// WithForm sets the request to be sent as application/x-www-form-urlencoded body
+func WithForm(request interface{}) CallOption {
+	return func(o *callOptions) {
+		o.request = request
+		o.bodyEncoder = FormEncoder
+	}
+}
+
+// WithMultipart sets the fields & files to be sent as multipart/form-data body
+func WithMultipart(form *MultipartForm) CallOption {
+	return func(o *callOptions) {
+		o.request = form
+		o.bodyEncoder = MultipartEncoder
+	}
+}
+
+// WithXML sets the request to be sent as xml body and parses the xml response into the result
+func WithXML(request interface{}) CallOption {
+	return func(o *callOptions) {
+		o.request = request
+		o.bodyEncoder = XMLEncoder
+		o.responseDecoder = XMLDecoder
+	}
+}
+
+// WithBaseURL replaces the APIURL of the client for the call
+func WithBaseURL(baseURL string) CallOption {
+	return func(o *callOptions) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithURL calls the full url given instead of the path of the client
+func WithURL(fullURL string) CallOption {
+	return func(o *callOptions) {
+		o.fullURL = fullURL
+	}
+}
+
+// WithQueryParams appends the json tagged fields of the query params struct pointer into the path,
+// the token of the APIKey authorization is set into its APIKey field
+func WithQueryParams(queryParams interface{}) CallOption {
+	return func(o *callOptions) {
+		o.queryParams = queryParams
+	}
+}
+
+// WithHeader adds the header into the request
+func WithHeader(key string, value string) CallOption {
+	return func(o *callOptions) {
+		o.header.Add(key, value)
+	}
+}
+
+// WithAcknowledge registers the request to be acknowledged on the commit or rollback of the request context
+func WithAcknowledge() CallOption {
+	return func(o *callOptions) {
+		o.isAcknowledgeNeeded = true
+	}
+}
+
+// WithoutLog skips the client request log and the acknowledge
+func WithoutLog() CallOption {
+	return func(o *callOptions) {
+		o.isWithoutLog = true
+	}
+}
+
+// WithLogAllMethods logs the GET request too, it's not logged by default
+func WithLogAllMethods() CallOption {
+	return func(o *callOptions) {
+		o.isLogAllMethods = true
+	}
+}
+
+// WithRawError returns the raw response body as the error message instead of parsing it
+func WithRawError() CallOption {
+	return WithErrorDecoder(RawErrorDecoder)
+}
+
+// WithErrorDecoder decodes the error response of the call by the decoder instead of the decoder of the client
+func WithErrorDecoder(decoder ErrorDecoder) CallOption {
+	return func(o *callOptions) {
+		o.errorDecoder = decoder
+	}
+}
+
+// WithCaching falls back to the last cached response of the client cache when the client is unavailable
+func WithCaching() CallOption {
+	return func(o *callOptions) {
+		o.isCaching = true
+	}
+}
+
+// WithRedisCache serves the response from redis for the duration,
+// keyPath replaces the path used as the cache key when it's not empty
+func WithRedisCache(duration time.Duration, keyPath string) CallOption {
+	return func(o *callOptions) {
+		o.isRedisCaching = true
+		o.redisCacheDuration = duration
+		o.redisCacheKeyPath = keyPath
+	}
+}
+
+// WithCircuitBreaker calls the client through the hystrix command of the client name
+func WithCircuitBreaker() CallOption {
+	return func(o *callOptions) {
+		o.isCircuitBreaker = true
+	}
+}
+
+// WithRetries replaces the MaxNetworkRetries of the client for the call
+func WithRetries(maxNetworkRetries int) CallOption {
+	return func(o *callOptions) {
+		o.maxNetworkRetries = &maxNetworkRetries
+	}
+}
+
+// WithRetryPolicy replaces the retry policy of the client for the call
+func WithRetryPolicy(policy RetryPolicy) CallOption {
+	return func(o *callOptions) {
+		o.retryPolicy = &policy
+	}
+}
+
+// WithIdempotencyKey sets the idempotency key header so the non idempotent request can be retried
+func WithIdempotencyKey(key string) CallOption {
+	return func(o *callOptions) {
+		o.header.Set(HeaderIdempotencyKey, key)
+	}
+}
+
+// WithMiddleware adds the middlewares right before the request is authorized and sent
+func WithMiddleware(middlewares ...CallMiddleware) CallOption {
+	return func(o *callOptions) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// isCallFailed returns true when the response error represents a failed call
+func isCallFailed(errDo *ResponseError) bool {
+	return errDo != nil && (errDo.Error != nil || errDo.Message != "")
+}
+
+// newHTTPRequest creates a new http request of the call, it's cancelled along with the context
+func (call *CallRequest) newHTTPRequest(ctx *context.Context) (*http.Request, error) {
+	req, err := http.NewRequest(string(call.Method), call.URL, bytes.NewBuffer(call.Body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(*ctx)
+
+	for key, values := range call.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	return req, nil
+}
+
+// holderName returns the type name of the request reserved by the acknowledge
+func holderName(request interface{}) string {
+	requestType := reflect.TypeOf(request)
+	if requestType == nil {
+		return ""
+	}
+	if requestType.Kind() == reflect.Ptr {
+		requestType = requestType.Elem()
+	}
+	return requestType.Name()
+}
+
+// backgroundContext returns the context of the logs written regardless of the request context
+func backgroundContext(ctx *context.Context) context.Context {
+	tempCurrentAccount := appcontext.CurrentAccount(ctx)
+	if tempCurrentAccount == nil {
+		defaultValue := 0
+		tempCurrentAccount = &defaultValue
+	}
+	return context.WithValue(context.Background(), appcontext.KeyCurrentAccount, *tempCurrentAccount)
+}
+
+func (c *HTTPClient) newCallRequest(method Method, path string, options *callOptions) (*CallRequest, *ResponseError) {
+	var body []byte
+	var err error
+	callPath := path
+	contentType := defaultContentType
+
+	if options.isRawBody {
+		body = options.rawBody
+	} else if options.request != nil && options.request != "" {
+		body, contentType, err = options.encoder().Encode(options.request)
+		if err != nil {
+			return nil, &ResponseError{
+				Error: err,
+			}
+		}
+	}
+
+	if options.queryParams != nil {
+		for _, authorizationType := range c.authorizationTypes() {
+			if authorizationType.HeaderName == "APIKey" {
+				field := reflect.ValueOf(options.queryParams).Elem().FieldByName("APIKey")
+				if field.IsValid() {
+					field.SetString(authorizationType.Token)
+				}
+			}
+		}
+		path = ParseQueryParams(path, options.queryParams)
+	}
+
+	callURL := options.fullURL
+	if callURL == "" {
+		baseURL := c.APIURL
+		if options.baseURL != "" {
+			baseURL = options.baseURL
+		}
+
+		urlPath, err := url.Parse(fmt.Sprintf("%s/%s", baseURL, path))
+		if err != nil {
+			return nil, &ResponseError{
+				Error: err,
+			}
+		}
+		callURL = urlPath.String()
+	}
+
+	header := http.Header{}
+	for _, authorizationType := range c.authorizationTypes() {
+		if authorizationType.HeaderType != "APIKey" {
+			header.Add(authorizationType.HeaderName, fmt.Sprintf("%s%s", authorizationType.HeaderTypeValue, authorizationType.Token))
+		}
+	}
+	header.Set("Content-Type", contentType)
+	for key, values := range options.header {
+		if http.CanonicalHeaderKey(key) == "Content-Type" {
+			header.Del(key)
+		}
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+
+	return &CallRequest{
+		Method:  method,
+		Path:    callPath,
+		URL:     callURL,
+		Header:  header,
+		Body:    body,
+		Request: options.request,
+		options: options,
+	}, nil
+}
+
+// send sends the request of the call, it's the last handler of the pipeline
+func (c *HTTPClient) send(ctx *context.Context, call *CallRequest) (string, *ResponseError) {
+	req, err := call.newHTTPRequest(ctx)
+	if err != nil {
+		return "", &ResponseError{
+			Error: err,
+		}
+	}
+
+	policy := c.retryPolicy()
+	if call.options.retryPolicy != nil {
+		policy = withRetryDefaults(*call.options.retryPolicy)
+	}
+	if call.options.maxNetworkRetries != nil {
+		policy.MaxAttempts = *call.options.maxNetworkRetries + 1
+	}
+
+	decoder := c.errorDecoder()
+	if call.options.errorDecoder != nil {
+		decoder = call.options.errorDecoder
+	}
+
+	onAttempt := func(attempt *ClientRequestAttempt) {
+		call.Attempts = append(call.Attempts, attempt)
+	}
+	if call.options.isStream {
+		res, _, errDo := c.roundTrip(req, policy, decoder, true, call.beforeAttempt, onAttempt)
+		if !isCallFailed(errDo) {
+			call.Response = res
+		}
+		return "", errDo
+	}
+	return c.do(req, policy, decoder, call.beforeAttempt, onAttempt)
+}
+
+// logMiddleware writes the client request log of the call, GET is only logged with WithLogAllMethods
+func (c *HTTPClient) logMiddleware(next CallHandler) CallHandler {
+	return func(ctx *context.Context, call *CallRequest) (string, *ResponseError) {
+		if call.options.isWithoutLog || (call.Method == GET && !call.options.isLogAllMethods) {
+			return next(ctx, call)
+		}
+
+		encoder := call.options.encoder()
+		if call.options.isRawBody {
+			encoder = JSONEncoder
+		}
+		requestRaw, err := encoder.Log(call.Request, call.Body)
+		if err != nil {
+			return "", &ResponseError{
+				Error: err,
+			}
+		}
+		call.RequestRaw = requestRaw
+
+		// the curl command is built from the redacted request
+		redactor := c.redactor()
+		header := redactor.Header(call.Header)
+		req, err := http.NewRequest(string(call.Method), redactor.URL(call.URL), bytes.NewBufferString(redactor.logBody(encoder, call.Body)))
+		if err != nil {
+			return "", &ResponseError{
+				Error: err,
+			}
+		}
+		req.Header = header
+
+		clientID, clientType := determineClient(ctx)
+		command, _ := http2curl.GetCurlCommand(req)
+		backgroundContext := backgroundContext(ctx)
+		call.Log = &ClientRequestLog{
+			ClientID:       clientID,
+			ClientType:     clientType,
+			Method:         string(call.Method),
+			URL:            call.URL,
+			Header:         fmt.Sprintf("%v", header),
+			Request:        requestRaw,
+			Status:         "calling",
+			HTTPStatusCode: 0,
+			ReferenceID:    appcontext.RequestReferenceID(ctx),
+			Response:       "{}",
+			CURL:           command.String(),
+			Metadata:       types.Metadata{},
+		}
+
+		// the log keeps the raw url & request for the acknowledge, only its redacted copy is persisted
+		persist := func(persist func(ctx *context.Context, clientRequestLog *ClientRequestLog) *ClientRequestLog) {
+			if clientRequestLog := persist(&backgroundContext, redactor.ClientRequestLog(call.Log, call.options.decoder())); clientRequestLog != nil {
+				call.Log.ID = clientRequestLog.ID
+			}
+		}
+		persist(c.clientRequestLogStorage.Insert)
+		call.updateLog = func() {
+			persist(c.clientRequestLogStorage.Update)
+		}
+
+		response, errDo := next(ctx, call)
+		if call.Log.Metadata == nil {
+			call.Log.Metadata = types.Metadata{}
+		}
+		if len(call.Attempts) > 0 {
+			call.Log.Metadata["attempts"] = call.Attempts
+		}
+		if call.RateLimitWait > 0 {
+			call.Log.Metadata["rateLimitWaitMs"] = call.RateLimitWait.Milliseconds()
+		}
+		if call.Response != nil {
+			// only the metadata of the streamed body is logged
+			call.Log.Metadata["contentType"] = call.Response.Header.Get("Content-Type")
+			call.Log.Metadata["contentLength"] = call.Response.ContentLength
+		}
+		if isCallFailed(errDo) {
+			call.Log.HTTPStatusCode = errDo.StatusCode
+			call.Log.Status = "failed"
+			call.Log.Response = response
+			persist(c.clientRequestLogStorage.Update)
+			return response, errDo
+		}
+
+		type TransactionID struct {
+			ID int `json:"id"`
+		}
+		var transactionID TransactionID
+		json.Unmarshal([]byte(response), &transactionID)
+
+		call.Log.TransactionID = transactionID.ID
+		if errDo != nil {
+			call.Log.HTTPStatusCode = errDo.StatusCode
+		}
+		call.Log.Status = "success"
+		call.Log.Response = response
+		persist(c.clientRequestLogStorage.Update)
+
+		return response, errDo
+	}
+}
+
+// acknowledgeMiddleware registers the logged request to be acknowledged on the commit or rollback of the request context
+func (c *HTTPClient) acknowledgeMiddleware(next CallHandler) CallHandler {
+	return func(ctx *context.Context, call *CallRequest) (string, *ResponseError) {
+		response, errDo := next(ctx, call)
+		if isCallFailed(errDo) || call.Log == nil || !call.options.isAcknowledgeNeeded || appcontext.RequestStatus(ctx) != nil {
+			return response, errDo
+		}
+
+		currentClientRequests := []*ClientRequest{}
+		temp := appcontext.ClientRequests(ctx)
+		if temp != nil {
+			currentClientRequests = temp.([]*ClientRequest)
+		}
+		currentClientRequests = append(currentClientRequests, &ClientRequest{
+			Client:      c,
+			Request:     call.Log,
+			Body:        call.Body,
+			ContentType: call.Header.Get("Content-Type"),
+		})
+		*ctx = context.WithValue(*ctx, appcontext.KeyClientRequests, currentClientRequests)
+
+		backgroundContext := backgroundContext(ctx)
+		// ignore when error occurs
+		_ = c.acknowledgeRequestService.Create(&backgroundContext, &AcknowledgeRequest{
+			RequestID:          call.Log.ID,
+			CommitStatus:       "on_progress",
+			ReservedHolder:     c.redactor().Metadata(call.RequestRaw),
+			ReservedHolderName: holderName(call.Request),
+			Message:            "",
+		})
+
+		return response, errDo
+	}
+}
+
+// clientCacheMiddleware stores the response into the client cache and serves the last cached response when the call fails
+func (c *HTTPClient) clientCacheMiddleware(next CallHandler) CallHandler {
+	return func(ctx *context.Context, call *CallRequest) (string, *ResponseError) {
+		cachingKey := call.URL
+		if call.Method != GET {
+			cachingKey = cachingKey + string(call.Body)
+		}
+
+		isAllowed, errClientCache := c.clientCacheService.IsClientNeedToBeCache(ctx, call.URL, string(call.Method))
+		if errClientCache != nil {
+			fmt.Printf("\nFailed to IsClientNeedToBeCache while collecting caching information: %v", errClientCache)
+		}
+
+		response, errDo := next(ctx, call)
+		if !isAllowed {
+			return response, errDo
+		}
+		if isCallFailed(errDo) {
+			return c.collectClientCache(ctx, call.Method, cachingKey, response, errDo)
+		}
+
+		c.storeClientCache(ctx, call.Method, cachingKey, response)
+		return response, errDo
+	}
+}
+
+// collectClientCache returns the last cached response of the failed call
+func (c *HTTPClient) collectClientCache(ctx *context.Context, method Method, cachingKey string, response string, errDo *ResponseError) (string, *ResponseError) {
+	clientCache, errClientCache := c.clientCacheService.GetClientCacheByURL(ctx, &GetClientCacheByURLParams{
+		URL:      cachingKey,
+		Method:   string(method),
+		IsActive: true,
+	})
+	if errClientCache != nil {
+		fmt.Printf("\nFailed to GetClientCacheByURL while collecting caching: %v", errClientCache)
+		fmt.Printf("\n\tParams: %#v", GetClientCacheByURLParams{
+			URL:      c.redactor().URL(cachingKey),
+			Method:   string(method),
+			IsActive: true,
+		})
+		return response, errDo
+	}
+
+	cachedResponse, errJSON := json.Marshal(clientCache.Response)
+	if errJSON != nil {
+		fmt.Printf("\nFailed to json.Marshal to convert cached response while doing collecting caching data: %v", errJSON)
+		return response, errDo
+	}
+
+	fmt.Printf("\n\n============================================================\n")
+	fmt.Printf("\nFailed to call client: %#v\n", errDo)
+	fmt.Printf("\n\tSuccess collecting last cached and omitting error client\n")
+	fmt.Printf("\n============================================================\n\n")
+	return string(cachedResponse), nil
+}
+
+// storeClientCache creates or updates the client cache of the response
+func (c *HTTPClient) storeClientCache(ctx *context.Context, method Method, cachingKey string, response string) {
+	isExist := true
+	currentClientCache, errClientCache := c.clientCacheService.GetClientCacheByURL(ctx, &GetClientCacheByURLParams{
+		URL:      cachingKey,
+		Method:   string(method),
+		IsActive: false,
+	})
+	if errClientCache != nil {
+		if errClientCache.Message != "data is not found" {
+			fmt.Printf("\nFailed to GetClientCacheByURL while collecting caching in order to update cache: %v", errClientCache)
+			fmt.Printf("\n\tParams: %#v", GetClientCacheByURLParams{
+				URL:      c.redactor().URL(cachingKey),
+				Method:   string(method),
+				IsActive: false,
+			})
+		}
+		isExist = false
+	}
+
+	responseInMap := types.Metadata{}
+	if response != "" {
+		errJSON := json.Unmarshal([]byte(response), &responseInMap)
+		if errJSON != nil {
+			fmt.Printf("\nFailed to json.Unmarshal to convert response while doing caching: %v", errJSON)
+		}
+	}
+
+	if isExist {
+		// update cache
+		_, errClientCache = c.clientCacheService.UpdateClientCache(ctx, currentClientCache.ID, &UpdateClientCacheParams{
+			URL:          currentClientCache.URL,
+			Method:       currentClientCache.Method,
+			ClientID:     currentClientCache.ClientID,
+			ClientName:   currentClientCache.ClientName,
+			Response:     responseInMap,
+			LastAccessed: time.Now().UTC(),
+		})
+		if errClientCache != nil {
+			fmt.Printf("\nFailed to UpdateClientCache while doing caching: %v", errClientCache)
+		}
+		return
+	}
+
+	// create new cache
+	tempClientID := appcontext.ClientID(ctx)
+	clientID := 0
+	if tempClientID != nil {
+		clientID = *tempClientID
+	}
+
+	_, errClientCache = c.clientCacheService.CreateClientCache(ctx, &CreateClientCacheParams{
+		URL:          cachingKey,
+		Method:       string(method),
+		ClientID:     clientID,
+		ClientName:   c.ClientName,
+		Response:     responseInMap,
+		LastAccessed: time.Now().UTC(),
+	})
+	if errClientCache != nil {
+		fmt.Printf("\nFailed to CreateClientCache while doing caching: %v", errClientCache)
+	}
+}
+
+// redisCacheMiddleware serves the response from redis when it's cached and caches the successful response
+func (c *HTTPClient) redisCacheMiddleware(next CallHandler) CallHandler {
+	return func(ctx *context.Context, call *CallRequest) (string, *ResponseError) {
+		key := "apicaching:" + call.URL
+		if call.options.redisCacheKeyPath != "" {
+			baseURL := c.APIURL
+			if call.options.baseURL != "" {
+				baseURL = call.options.baseURL
+			}
+
+			urlPath, err := url.Parse(fmt.Sprintf("%s/%s", baseURL, call.options.redisCacheKeyPath))
+			if err != nil {
+				return "", &ResponseError{
+					Error: err,
+				}
+			}
+			key = "apicaching:" + urlPath.String()
+		}
+
+		//collect from redis if already exist
+		val, errRedis := c.redisClient.Get(key).Result()
+		if errRedis != nil {
+			log.Printf(`
+		======================================================================
+		Error Collecting Caching in "Call":
+		"key": %s
+		Error: %v
+		======================================================================
+		`, c.redactor().URL(key), errRedis)
+		}
+		if val != "" && (call.options.decoder() != JSONDecoder || json.Valid([]byte(val))) {
+			return val, nil
+		}
+
+		response, errDo := next(ctx, call)
+		if isCallFailed(errDo) || response == "" {
+			return response, errDo
+		}
+
+		if errRedis = c.redisClient.Set(key, response, call.options.redisCacheDuration).Err(); errRedis != nil {
+			log.Printf(`
+			======================================================================
+			Error Storing Caching in "Call":
+			"key": %s,
+			Error: %v,
+			======================================================================
+			`, c.redactor().URL(key), errRedis)
+		}
+
+		return response, errDo
+	}
+}
+
+// circuitBreakerMiddleware runs the call guarded by the circuit breaker of the client or its endpoint,
+// the call rejected by the breaker fails with ErrCircuitOpen
+func (c *HTTPClient) circuitBreakerMiddleware(next CallHandler) CallHandler {
+	return func(ctx *context.Context, call *CallRequest) (string, *ResponseError) {
+		var mu sync.Mutex
+		var response string
+		var errDo *ResponseError
+
+		err := c.breaker(call.Path).Do(func() error {
+			res, errRes := next(ctx, call)
+
+			mu.Lock()
+			defer mu.Unlock()
+			response, errDo = res, errRes
+			if !isCallFailed(errDo) {
+				return nil
+			}
+			if errDo.Error != nil {
+				return errDo.Error
+			}
+			return errors.New(errDo.Message)
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil && !isCallFailed(errDo) {
+			return "", &ResponseError{
+				Message: err.Error(),
+				Error:   err,
+			}
+		}
+		return response, errDo
+	}
+}
+
+// handler returns the pipeline of the middlewares enabled by the options ending with send
+func (c *HTTPClient) handler(options *callOptions) CallHandler {
+	middlewares := []CallMiddleware{}
+	if options.isRedisCaching {
+		middlewares = append(middlewares, c.redisCacheMiddleware)
+	}
+	if options.isCircuitBreaker {
+		middlewares = append(middlewares, c.circuitBreakerMiddleware)
+	}
+	middlewares = append(middlewares, c.acknowledgeMiddleware, c.logMiddleware)
+	if options.isCaching {
+		middlewares = append(middlewares, c.clientCacheMiddleware)
+	}
+	if c.RateLimitConfig != nil {
+		middlewares = append(middlewares, c.rateLimitMiddleware)
+	}
+	middlewares = append(middlewares, options.middlewares...)
+	if c.AuthProvider != nil {
+		middlewares = append(middlewares, c.authMiddleware)
+	}
+
+	handler := CallHandler(c.send)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Call calls the client through the pipeline of the middlewares enabled by the options
+func (c *HTTPClient) Call(ctx *context.Context, method Method, path string, opts ...CallOption) *ResponseError {
+	options := newCallOptions(opts)
+	call, errDo := c.newCallRequest(method, path, options)
+	if errDo != nil {
+		return errDo
+	}
+
+	response, errDo := c.handler(options)(ctx, call)
+	if isCallFailed(errDo) {
+		return errDo
+	}
+
+	if response != "" && options.result != nil {
+		err := options.decoder().Decode([]byte(response), options.result)
+		if err != nil {
+			return &ResponseError{
+				Error: err,
+			}
+		}
+	}
+
+	return errDo
+}
@@ -1,24 +1,18 @@
 package client
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"net/http"
-	"net/url"
 	"reflect"
+	"strconv"
+	"sync"
 	"time"
 
-	"moul.io/http2curl"
-
 	"github.com/afex/hystrix-go/hystrix"
 	"github.com/go-redis/redis"
-	"github.com/payfazz/commerce-kit/appcontext"
 	"github.com/payfazz/commerce-kit/types"
 )
 
@@ -42,8 +36,12 @@ type ResponseError struct {
 	Fields     types.Metadata `json:"-"`
 	StatusCode int            `json:"statusCode"`
 	Error      error          `json:"error"`
+	// Retryable is true when the request may succeed on retry
+	Retryable bool `json:"retryable"`
+	// Raw is the response body of the provider
+	Raw []byte `json:"-"`
 
-	// Error Response for Anteraja
+	// Error Response for Anteraja, it's filled by the AnterajaErrorDecoder
 	Status int    `json:"status"`
 	Info   string `json:"info"`
 }
@@ -83,6 +81,9 @@ const minNetworkRetriesDelay = 500 * time.Millisecond
 
 var httpClient = &http.Client{Timeout: defaultHTTPTimeout}
 
+// authorizationMu guards the AuthorizationTypes of the clients
+var authorizationMu sync.RWMutex
+
 // GenericHTTPClient represents an interface to generalize an object to implement HTTPClient
 type GenericHTTPClient interface {
 	Do(req *http.Request) (string, *ResponseError)
@@ -99,6 +100,12 @@ type GenericHTTPClient interface {
 	AddAuthentication(ctx *context.Context, authorizationType AuthorizationType)
 }
 
+// Caller represents the GenericHTTPClient supporting the Call with the call options
+type Caller interface {
+	GenericHTTPClient
+	Call(ctx *context.Context, method Method, path string, opts ...CallOption) *ResponseError
+}
+
 // HTTPClient represents the service http client
 type HTTPClient struct {
 	clientRequestLogStorage   ClientRequestLogStorage
@@ -111,1772 +118,276 @@ type HTTPClient struct {
 	UseNormalSleep            bool
 	AuthorizationTypes        []AuthorizationType
 	ClientName                string
-}
-
-func (c *HTTPClient) shouldRetry(err error, res *http.Response, retry int) bool {
-	if retry >= c.MaxNetworkRetries {
-		return false
-	}
-
-	if err != nil {
-		return true
-	}
-
-	return false
-}
-
-func (c *HTTPClient) sleepTime(numRetries int) time.Duration {
-	if c.UseNormalSleep {
-		return 0
-	}
-
-	// exponentially backoff by 2^numOfRetries
-	delay := minNetworkRetriesDelay + minNetworkRetriesDelay*time.Duration(1<<uint(numRetries))
-	if delay > maxNetworkRetriesDelay {
-		delay = maxNetworkRetriesDelay
-	}
-
-	// generate random jitter to prevent thundering herd problem
-	jitter := rand.Int63n(int64(delay / 4))
-	delay -= time.Duration(jitter)
-
-	if delay < minNetworkRetriesDelay {
-		delay = minNetworkRetriesDelay
-	}
-
-	return delay
+	RetryPolicy               *RetryPolicy
+	BreakerConfig             *BreakerConfig
+	RateLimitConfig           *RateLimitConfig
+	AuthProvider              AuthProvider
+	RequestSigner             RequestSigner
+	Transport                 *TransportConfig
+	RedactionPolicy           *RedactionPolicy
+	ErrorDecoder              ErrorDecoder
 }
 
 // Do calls the api http request and parse the response into v
 func (c *HTTPClient) Do(req *http.Request) (string, *ResponseError) {
-	var res *http.Response
-	var err error
-
-	for retry := 0; ; {
-		res, err = c.HTTPClient.Do(req)
-
-		if !c.shouldRetry(err, res, retry) {
-			break
-		}
-
-		sleepDuration := c.sleepTime(retry)
-		retry++
-
-		time.Sleep(sleepDuration)
-	}
-	if err != nil {
-		return "", &ResponseError{
-			Code:    "",
-			Message: "",
-			Fields:  nil,
-			Error:   err,
-		}
-	}
-	defer res.Body.Close()
-
-	resBody, err := ioutil.ReadAll(res.Body)
-	res.Body.Close()
-	if err != nil {
-		return "", &ResponseError{
-			Code:       string(res.StatusCode),
-			Message:    "",
-			Fields:     nil,
-			StatusCode: res.StatusCode,
-			Error:      err,
-		}
-	}
-
-	errResponse := &ResponseError{
-		Code:       string(res.StatusCode),
-		Message:    "",
-		Fields:     nil,
-		StatusCode: res.StatusCode,
-		Error:      nil,
-	}
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		err = json.Unmarshal([]byte(string(resBody)), errResponse)
-		if err != nil {
-			errResponse.Error = err
-		}
-		if errResponse.Info != "" {
-			errResponse.Message = errResponse.Info
-		}
-		if errResponse.Status != 0 {
-			errResponse.StatusCode = errResponse.Status
-		}
-		errResponse.Error = fmt.Errorf("Error while calling %s: %v", req.URL.String(), errResponse.Message)
-
-		return "", errResponse
-	}
+	return c.do(req, c.retryPolicy(), c.errorDecoder(), nil, nil)
+}
 
+// do calls the api http request retrying it according to the policy, beforeAttempt is called before every attempt
+// and its error stops the call, onAttempt is called after every attempt.
+// The non 2xx response is decoded into the ResponseError by the error decoder
+func (c *HTTPClient) do(req *http.Request, policy *RetryPolicy, decoder ErrorDecoder, beforeAttempt func() *ResponseError, onAttempt func(attempt *ClientRequestAttempt)) (string, *ResponseError) {
+	_, resBody, errResponse := c.roundTrip(req, policy, decoder, false, beforeAttempt, onAttempt)
 	return string(resBody), errResponse
 }
 
-// CallClient do call client
-func (c *HTTPClient) CallClient(ctx *context.Context, path string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
-	var jsonData []byte
+// roundTrip sends the request retrying it according to the policy and returns the response with its body,
+// the body of the 2xx response is left open to be streamed when isStream is true
+func (c *HTTPClient) roundTrip(req *http.Request, policy *RetryPolicy, decoder ErrorDecoder, isStream bool, beforeAttempt func() *ResponseError, onAttempt func(attempt *ClientRequestAttempt)) (*http.Response, []byte, *ResponseError) {
+	var res *http.Response
+	var resBody []byte
 	var err error
-	var response string
-	var errDo *ResponseError
 
-	if request != nil && request != "" {
-		jsonData, err = json.Marshal(request)
+	var body []byte
+	if c.RequestSigner != nil && req.GetBody != nil {
+		reader, err := req.GetBody()
 		if err != nil {
-			errDo = &ResponseError{
+			return nil, nil, &ResponseError{
 				Error: err,
 			}
-			return errDo
-		}
-	}
-
-	urlPath, err := url.Parse(fmt.Sprintf("%s/%s", c.APIURL, path))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	req, err := http.NewRequest(string(method), urlPath.String(), bytes.NewBuffer(jsonData))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	for _, authorizationType := range c.AuthorizationTypes {
-		if authorizationType.HeaderType != "APIKey" {
-			req.Header.Add(authorizationType.HeaderName, fmt.Sprintf("%s%s", authorizationType.HeaderTypeValue, authorizationType.Token))
 		}
-	}
-	req.Header.Add("Content-Type", "application/json")
-
-	clientID, clientType := determineClient(ctx)
-	requestRaw := types.Metadata{}
-	if request != nil && request != "" {
-		err = json.Unmarshal(jsonData, &requestRaw)
+		body, err = ioutil.ReadAll(reader)
 		if err != nil {
-			errDo = &ResponseError{
+			return nil, nil, &ResponseError{
 				Error: err,
 			}
-			return errDo
-		}
-	}
-
-	clientRequestLog := &ClientRequestLog{}
-	tempCurrentAccount := appcontext.CurrentAccount(ctx)
-	if tempCurrentAccount == nil {
-		defaultValue := 0
-		tempCurrentAccount = &defaultValue
-	}
-	command, _ := http2curl.GetCurlCommand(req)
-	requestReferenceID := appcontext.RequestReferenceID(ctx)
-	backgroundContext := context.WithValue(context.Background(), appcontext.KeyCurrentAccount, *tempCurrentAccount)
-	if method != GET {
-		clientRequestLog = c.clientRequestLogStorage.Insert(&backgroundContext, &ClientRequestLog{
-			ClientID:       clientID,
-			ClientType:     clientType,
-			Method:         string(method),
-			URL:            urlPath.String(),
-			Header:         fmt.Sprintf("%v", req.Header),
-			Request:        requestRaw,
-			Status:         "calling",
-			HTTPStatusCode: 0,
-			ReferenceID:    requestReferenceID,
-			Response:       "{}",
-			CURL:           command.String(),
-		})
-	}
-
-	response, errDo = c.Do(req)
-	if errDo != nil && (errDo.Error != nil || errDo.Message != "") {
-		if method != GET {
-			clientRequestLog.HTTPStatusCode = errDo.StatusCode
-			clientRequestLog.Status = "failed"
-			clientRequestLog.Response = response
-			clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
 		}
-		return errDo
-	}
-
-	type TransactionID struct {
-		ID int `json:"id"`
 	}
-	var transactionID TransactionID
-	json.Unmarshal([]byte(response), &transactionID)
 
-	if method != GET {
-		clientRequestLog.TransactionID = transactionID.ID
-		if errDo != nil {
-			clientRequestLog.HTTPStatusCode = errDo.StatusCode
+	startedAt := time.Now()
+	for attempt := 1; ; attempt++ {
+		// the body of the previous attempt has been consumed
+		if attempt > 1 && req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				break
+			}
 		}
-		clientRequestLog.Status = "success"
-		clientRequestLog.Response = response
-		clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-
-		requestStatus := appcontext.RequestStatus(ctx)
-		if requestStatus == nil && isAcknowledgeNeeded {
-			currentClientRequests := []*ClientRequest{}
-			temp := appcontext.ClientRequests(ctx)
-			if temp != nil {
-				currentClientRequests = temp.([]*ClientRequest)
+		if beforeAttempt != nil {
+			if errAttempt := beforeAttempt(); errAttempt != nil {
+				return nil, nil, errAttempt
 			}
-			currentClientRequests = append(currentClientRequests, &ClientRequest{
-				Client:  c,
-				Request: clientRequestLog,
-			})
-			*ctx = context.WithValue(*ctx, appcontext.KeyClientRequests, currentClientRequests)
-			// ignore when error occurs
-			_ = c.acknowledgeRequestService.Create(&backgroundContext, &AcknowledgeRequest{
-				RequestID:          clientRequestLog.ID,
-				CommitStatus:       "on_progress",
-				ReservedHolder:     requestRaw,
-				ReservedHolderName: reflect.TypeOf(request).Elem().Name(),
-				Message:            "",
-			})
 		}
-	}
-
-	if response != "" && result != nil {
-		err = json.Unmarshal([]byte(response), result)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
+		if c.RequestSigner != nil {
+			err = c.RequestSigner.Sign(req, body)
+			if err != nil {
+				break
 			}
-			return errDo
 		}
-	}
-
-	return errDo
-}
 
-// CallClientWithCaching do call client if client is unavailable try to collect response from cache when the time is still fulfill
-func (c *HTTPClient) CallClientWithCaching(ctx *context.Context, path string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
-	var jsonData []byte
-	var err error
-	var response string
-	var errDo *ResponseError
+		attemptStartedAt := time.Now()
+		res, err = c.HTTPClient.Do(req)
+		if err == nil && !(isStream && res.StatusCode >= 200 && res.StatusCode < 300) {
+			resBody, err = ioutil.ReadAll(res.Body)
+			res.Body.Close()
+		}
 
-	if request != nil && request != "" {
-		jsonData, err = json.Marshal(request)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
+		isRetry, delay := c.nextAttempt(policy, req, res, err, attempt, startedAt)
+		if onAttempt != nil {
+			clientRequestAttempt := &ClientRequestAttempt{
+				Attempt:    attempt,
+				StartedAt:  attemptStartedAt.UTC(),
+				DurationMs: time.Since(attemptStartedAt).Milliseconds(),
+				DelayMs:    delay.Milliseconds(),
 			}
-			return errDo
+			if res != nil {
+				clientRequestAttempt.StatusCode = res.StatusCode
+			}
+			if err != nil {
+				clientRequestAttempt.Error = err.Error()
+			}
+			onAttempt(clientRequestAttempt)
 		}
-	}
 
-	urlPath, err := url.Parse(fmt.Sprintf("%s/%s", c.APIURL, path))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
+		if !isRetry {
+			break
 		}
-		return errDo
-	}
 
-	req, err := http.NewRequest(string(method), urlPath.String(), bytes.NewBuffer(jsonData))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
+		timer := time.NewTimer(delay)
+		isCancelled := false
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			err = req.Context().Err()
+			isCancelled = true
+		case <-timer.C:
 		}
-		return errDo
-	}
-
-	cachingKey := urlPath.String()
-	if method != GET {
-		cachingKey = cachingKey + string(jsonData)
-	}
-
-	for _, authorizationType := range c.AuthorizationTypes {
-		if authorizationType.HeaderType != "APIKey" {
-			req.Header.Add(authorizationType.HeaderName, fmt.Sprintf("%s%s", authorizationType.HeaderTypeValue, authorizationType.Token))
+		if isCancelled {
+			break
 		}
 	}
-	req.Header.Add("Content-Type", "application/json")
-
-	clientID, clientType := determineClient(ctx)
-	requestRaw := types.Metadata{}
-	if request != nil && request != "" {
-		err = json.Unmarshal(jsonData, &requestRaw)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
+	if err != nil && res == nil {
+		return nil, nil, &ResponseError{
+			Code:    "",
+			Message: "",
+			Fields:  nil,
+			Error:   err,
 		}
 	}
-
-	clientRequestLog := &ClientRequestLog{}
-	tempCurrentAccount := appcontext.CurrentAccount(ctx)
-	if tempCurrentAccount == nil {
-		defaultValue := 0
-		tempCurrentAccount = &defaultValue
-	}
-
-	command, _ := http2curl.GetCurlCommand(req)
-	requestReferenceID := appcontext.RequestReferenceID(ctx)
-	backgroundContext := context.WithValue(context.Background(), appcontext.KeyCurrentAccount, *tempCurrentAccount)
-	if method != GET {
-		clientRequestLog = c.clientRequestLogStorage.Insert(&backgroundContext, &ClientRequestLog{
-			ClientID:       clientID,
-			ClientType:     clientType,
-			Method:         string(method),
-			URL:            urlPath.String(),
-			Header:         fmt.Sprintf("%v", req.Header),
-			Request:        requestRaw,
-			Status:         "calling",
-			HTTPStatusCode: 0,
-			ReferenceID:    requestReferenceID,
-			Response:       "{}",
-			CURL:           command.String(),
-		})
+	if err != nil {
+		return nil, nil, &ResponseError{
+			Code:       strconv.Itoa(res.StatusCode),
+			Message:    "",
+			Fields:     nil,
+			StatusCode: res.StatusCode,
+			Error:      err,
+		}
 	}
 
-	isAllowed, errClientCache := c.clientCacheService.IsClientNeedToBeCache(ctx, urlPath.String(), string(method))
-	if errClientCache != nil {
-		fmt.Printf("\nFailed to IsClientNeedToBeCache while collecting caching information: %v", errClientCache)
+	errResponse := &ResponseError{
+		Code:       strconv.Itoa(res.StatusCode),
+		Message:    "",
+		Fields:     nil,
+		StatusCode: res.StatusCode,
+		Error:      nil,
 	}
-
-	isError := false
-	response, errDo = c.Do(req)
-	if errDo != nil && (errDo.Error != nil || errDo.Message != "") {
-		cachingKey := urlPath.String()
-		if method != GET {
-			clientRequestLog.HTTPStatusCode = errDo.StatusCode
-			clientRequestLog.Status = "failed"
-			clientRequestLog.Response = response
-			clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-		}
-
-		// Do check cache
-		isError = true
-		if !isAllowed {
-			return errDo
-		}
-
-		isSuccessCollectingCache := true
-
-		// collect cache
-		clientCache, errClientCache := c.clientCacheService.GetClientCacheByURL(ctx, &GetClientCacheByURLParams{
-			URL:      cachingKey,
-			Method:   string(method),
-			IsActive: true,
-		})
-		if errClientCache != nil {
-			fmt.Printf("\nFailed to GetClientCacheByURL while collecting caching: %v", errClientCache)
-			fmt.Printf("\n\tParams: %#v", GetClientCacheByURLParams{
-				URL:      cachingKey,
-				Method:   string(method),
-				IsActive: false,
-			})
-			isSuccessCollectingCache = false
-		}
-
-		var bytes []byte
-		var errJSON error
-		if isSuccessCollectingCache {
-			bytes, errJSON = json.Marshal(clientCache.Response)
-			if errJSON != nil {
-				fmt.Printf("\nFailed to json.Marshal to convert cached response while doing collecting caching data: %v", errJSON)
-				isSuccessCollectingCache = false
-			}
-		}
-
-		if isSuccessCollectingCache {
-			fmt.Printf("\n\n============================================================\n")
-			fmt.Printf("\nFailed to call client: %#v\n", errDo)
-			fmt.Printf("\n\tSuccess collecting last cached and omitting error client\n")
-			fmt.Printf("\n============================================================\n\n")
-			errDo = nil
-		}
-		response = string(bytes)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, nil, c.decodeError(decoder, policy, req, res, resBody)
 	}
 
-	if isAllowed && !isError {
-		// do caching
-		isExist := true
-		currentClientCache, errClientCache := c.clientCacheService.GetClientCacheByURL(ctx, &GetClientCacheByURLParams{
-			URL:      cachingKey,
-			Method:   string(method),
-			IsActive: false,
-		})
-		if errClientCache != nil {
-			if errClientCache.Message != "data is not found" {
-				fmt.Printf("\nFailed to GetClientCacheByURL while collecting caching in order to update cache: %v", errClientCache)
-				fmt.Printf("\n\tParams: %#v", GetClientCacheByURLParams{
-					URL:      urlPath.String(),
-					Method:   string(method),
-					IsActive: false,
-				})
-			}
-			isExist = false
-		}
-
-		responseInMap := types.Metadata{}
-		if response != "" {
-			errJSON := json.Unmarshal([]byte(response), &responseInMap)
-			if errJSON != nil {
-				fmt.Printf("\nFailed to json.Unmarshal to convert response while doing caching: %v", errJSON)
-			}
-		}
-
-		if isExist {
-			// update cache
-			currentClientCache.Response = types.Metadata{}
-			currentClientCache.Response = responseInMap
-			currentClientCache.LastAccessed = time.Now().UTC()
-
-			_, errClientCache = c.clientCacheService.UpdateClientCache(ctx, currentClientCache.ID, &UpdateClientCacheParams{
-				URL:          currentClientCache.URL,
-				Method:       currentClientCache.Method,
-				ClientID:     currentClientCache.ClientID,
-				ClientName:   currentClientCache.ClientName,
-				Response:     currentClientCache.Response,
-				LastAccessed: currentClientCache.LastAccessed,
-			})
-			if errClientCache != nil {
-				fmt.Printf("\nFailed to UpdateClientCache while doing caching: %v", errClientCache)
-			}
-		} else {
-			// create new cache
-			tempClientID := appcontext.ClientID(ctx)
-			clientID := 0
-			if tempClientID != nil {
-				clientID = *tempClientID
-			}
+	return res, resBody, errResponse
+}
 
-			_, errClientCache = c.clientCacheService.CreateClientCache(ctx, &CreateClientCacheParams{
-				URL:          cachingKey,
-				Method:       string(method),
-				ClientID:     clientID,
-				ClientName:   c.ClientName,
-				Response:     responseInMap,
-				LastAccessed: time.Now().UTC(),
-			})
-			if errClientCache != nil {
-				fmt.Printf("\nFailed to CreateClientCache while doing caching: %v", errClientCache)
-			}
-		}
+// legacyOptions returns the call options of the request & acknowledge arguments of the CallClient methods
+func legacyOptions(request interface{}, result interface{}, isAcknowledgeNeeded bool) []CallOption {
+	opts := []CallOption{WithRequest(request), WithResult(result)}
+	if isAcknowledgeNeeded {
+		opts = append(opts, WithAcknowledge())
 	}
+	return opts
+}
 
-	type TransactionID struct {
-		ID int `json:"id"`
+// legacyQueryParams returns the query params option of the customized error calls,
+// the query params are only applied when the client is authorized by APIKey
+func (c *HTTPClient) legacyQueryParams(queryParams interface{}) []CallOption {
+	if queryParams == nil {
+		return nil
 	}
-	var transactionID TransactionID
-	json.Unmarshal([]byte(response), &transactionID)
-
-	if method != GET {
-		clientRequestLog.TransactionID = transactionID.ID
-		if errDo != nil {
-			clientRequestLog.HTTPStatusCode = errDo.StatusCode
-		}
-		clientRequestLog.Status = "success"
-		clientRequestLog.Response = response
-		clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-
-		requestStatus := appcontext.RequestStatus(ctx)
-		if requestStatus == nil && isAcknowledgeNeeded {
-			currentClientRequests := []*ClientRequest{}
-			temp := appcontext.ClientRequests(ctx)
-			if temp != nil {
-				currentClientRequests = temp.([]*ClientRequest)
-			}
-			currentClientRequests = append(currentClientRequests, &ClientRequest{
-				Client:  c,
-				Request: clientRequestLog,
-			})
-			*ctx = context.WithValue(*ctx, appcontext.KeyClientRequests, currentClientRequests)
-			// ignore when error occurs
-			_ = c.acknowledgeRequestService.Create(&backgroundContext, &AcknowledgeRequest{
-				RequestID:          clientRequestLog.ID,
-				CommitStatus:       "on_progress",
-				ReservedHolder:     requestRaw,
-				ReservedHolderName: reflect.TypeOf(request).Elem().Name(),
-				Message:            "",
-			})
+	for _, authorizationType := range c.authorizationTypes() {
+		if authorizationType.HeaderName == "APIKey" && reflect.ValueOf(queryParams).Elem().FieldByName("APIKey").IsValid() {
+			return []CallOption{WithQueryParams(queryParams)}
 		}
 	}
+	return nil
+}
 
-	if response != "" && result != nil {
-		err = json.Unmarshal([]byte(response), result)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
+// CallClient do call client
+func (c *HTTPClient) CallClient(ctx *context.Context, path string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
+	return c.Call(ctx, method, path, legacyOptions(request, result, isAcknowledgeNeeded)...)
+}
 
-	return errDo
+// CallClientWithCaching do call client if client is unavailable try to collect response from cache when the time is still fulfill
+func (c *HTTPClient) CallClientWithCaching(ctx *context.Context, path string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
+	opts := append(legacyOptions(request, result, isAcknowledgeNeeded), WithCaching())
+	return c.Call(ctx, method, path, opts...)
 }
 
 // CallClientWithCachingInRedis call client with caching in redis
 func (c *HTTPClient) CallClientWithCachingInRedis(ctx *context.Context, durationInSecond int, path string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
-	var jsonData []byte
-	var err error
-	var response string
-	var errDo *ResponseError
+	opts := append(legacyOptions(request, result, isAcknowledgeNeeded), WithRedisCache(time.Second*time.Duration(durationInSecond), ""))
+	return c.Call(ctx, method, path, opts...)
+}
 
-	if request != nil && request != "" {
-		jsonData, err = json.Marshal(request)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
+// CallClientWithCachingInRedisWithDifferentKey call client with caching in redis with different key
+func (c *HTTPClient) CallClientWithCachingInRedisWithDifferentKey(ctx *context.Context, durationInSecond int, path string, pathToBeStoredAsKey string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
+	opts := append(legacyOptions(request, result, isAcknowledgeNeeded), WithRedisCache(time.Second*time.Duration(durationInSecond), pathToBeStoredAsKey))
+	return c.Call(ctx, method, path, opts...)
+}
 
-	urlPath, err := url.Parse(fmt.Sprintf("%s/%s", c.APIURL, path))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
+// CallClientWithCircuitBreaker do call client with circuit breaker (async)
+func (c *HTTPClient) CallClientWithCircuitBreaker(ctx *context.Context, path string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
+	opts := append(legacyOptions(request, result, isAcknowledgeNeeded), WithCircuitBreaker())
+	return c.Call(ctx, method, path, opts...)
+}
 
-	//collect from redis if already exist
-	val, errRedis := c.redisClient.Get("apicaching:" + urlPath.String()).Result()
-	if errRedis != nil {
-		log.Printf(`
-		======================================================================
-		Error Collecting Caching in "CallClientWithCachingInRedis":
-		"key": %s
-		Error: %v
-		======================================================================
-		`, "apicaching:"+urlPath.String(), errRedis)
-	}
+// CallClientWithoutLog do call client without log
+func (c *HTTPClient) CallClientWithoutLog(ctx *context.Context, path string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
+	return c.Call(ctx, method, path, WithRequest(request), WithResult(result), WithoutLog())
+}
 
-	if val != "" {
-		isSuccess := true
-		if errJSON := json.Unmarshal([]byte(val), &result); errJSON != nil {
-			log.Printf(`
-			======================================================================
-			Error Collecting Caching in "CallClientWithCachingInRedis":
-			"key": %s,
-			Error: %v,
-			======================================================================
-			`, "apicaching:"+urlPath.String(), errJSON)
-			isSuccess = false
-		}
-		if isSuccess {
-			return nil
-		}
-	}
+// CallClientWithBaseURLGiven do call client with base url given
+func (c *HTTPClient) CallClientWithBaseURLGiven(ctx *context.Context, url string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
+	return c.Call(ctx, method, "", WithURL(url), WithRequest(request), WithResult(result), WithoutLog())
+}
 
-	req, err := http.NewRequest(string(method), urlPath.String(), bytes.NewBuffer(jsonData))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
+// CallClientWithCustomizedError do call client with customized error
+func (c *HTTPClient) CallClientWithCustomizedError(ctx *context.Context, path string, method Method, queryParams interface{}, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
+	opts := append(legacyOptions(request, result, isAcknowledgeNeeded), WithRawError(), WithLogAllMethods())
+	opts = append(opts, c.legacyQueryParams(queryParams)...)
+	return c.Call(ctx, method, path, opts...)
+}
 
-	for _, authorizationType := range c.AuthorizationTypes {
-		if authorizationType.HeaderType != "APIKey" {
-			req.Header.Add(authorizationType.HeaderName, fmt.Sprintf("%s%s", authorizationType.HeaderTypeValue, authorizationType.Token))
-		}
-	}
-	req.Header.Add("Content-Type", "application/json")
+// CallClientWithCustomizedErrorAndCaching do call client with customized error and caching
+func (c *HTTPClient) CallClientWithCustomizedErrorAndCaching(ctx *context.Context, path string, method Method, queryParams interface{}, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
+	opts := append(legacyOptions(request, result, isAcknowledgeNeeded), WithRawError(), WithLogAllMethods(), WithCaching())
+	opts = append(opts, c.legacyQueryParams(queryParams)...)
+	return c.Call(ctx, method, path, opts...)
+}
 
-	clientID, clientType := determineClient(ctx)
-	requestRaw := types.Metadata{}
-	if request != nil && request != "" {
-		err = json.Unmarshal(jsonData, &requestRaw)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
+// CallClientWithRequestInBytes do call client with request in bytes and omit acknowledge process - specific case for consumer
+func (c *HTTPClient) CallClientWithRequestInBytes(ctx *context.Context, path string, method Method, request []byte, result interface{}) *ResponseError {
+	return c.Call(ctx, method, path, WithRawBody(request), WithResult(result))
+}
 
-	clientRequestLog := &ClientRequestLog{}
-	tempCurrentAccount := appcontext.CurrentAccount(ctx)
-	if tempCurrentAccount == nil {
-		defaultValue := 0
-		tempCurrentAccount = &defaultValue
-	}
+// authorizationTypes returns the authorization types of the client, it's safe to be read while AddAuthentication
+func (c *HTTPClient) authorizationTypes() []AuthorizationType {
+	authorizationMu.RLock()
+	defer authorizationMu.RUnlock()
 
-	command, _ := http2curl.GetCurlCommand(req)
-	requestReferenceID := appcontext.RequestReferenceID(ctx)
-	backgroundContext := context.WithValue(context.Background(), appcontext.KeyCurrentAccount, *tempCurrentAccount)
-	if method != GET {
-		clientRequestLog = c.clientRequestLogStorage.Insert(&backgroundContext, &ClientRequestLog{
-			ClientID:       clientID,
-			ClientType:     clientType,
-			Method:         string(method),
-			URL:            urlPath.String(),
-			Header:         fmt.Sprintf("%v", req.Header),
-			Request:        requestRaw,
-			Status:         "calling",
-			HTTPStatusCode: 0,
-			ReferenceID:    requestReferenceID,
-			Response:       "{}",
-			CURL:           command.String(),
-		})
-	}
+	return c.AuthorizationTypes
+}
 
-	response, errDo = c.Do(req)
-	if errDo != nil && (errDo.Error != nil || errDo.Message != "") {
-		if method != GET {
-			clientRequestLog.HTTPStatusCode = errDo.StatusCode
-			clientRequestLog.Status = "failed"
-			clientRequestLog.Response = response
-			clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-		}
-		return errDo
-	}
+// AddAuthentication do add authentication, it's safe for concurrent use
+func (c *HTTPClient) AddAuthentication(ctx *context.Context, authorizationType AuthorizationType) {
+	authorizationMu.Lock()
+	defer authorizationMu.Unlock()
 
-	type TransactionID struct {
-		ID int `json:"id"`
-	}
-	var transactionID TransactionID
-	json.Unmarshal([]byte(response), &transactionID)
+	// the slice is copied so the calls reading the current slice aren't affected
+	authorizationTypes := make([]AuthorizationType, len(c.AuthorizationTypes), len(c.AuthorizationTypes)+1)
+	copy(authorizationTypes, c.AuthorizationTypes)
 
-	if method != GET {
-		clientRequestLog.TransactionID = transactionID.ID
-		if errDo != nil {
-			clientRequestLog.HTTPStatusCode = errDo.StatusCode
+	isExist := false
+	for key, singleAuthorizationType := range authorizationTypes {
+		if singleAuthorizationType.HeaderType == authorizationType.HeaderType {
+			authorizationTypes[key].Token = authorizationType.Token
+			isExist = true
+			break
 		}
-		clientRequestLog.Status = "success"
-		clientRequestLog.Response = response
-		clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
+	}
 
-		requestStatus := appcontext.RequestStatus(ctx)
-		if requestStatus == nil && isAcknowledgeNeeded {
-			currentClientRequests := []*ClientRequest{}
-			temp := appcontext.ClientRequests(ctx)
-			if temp != nil {
-				currentClientRequests = temp.([]*ClientRequest)
-			}
-			currentClientRequests = append(currentClientRequests, &ClientRequest{
-				Client:  c,
-				Request: clientRequestLog,
-			})
-			*ctx = context.WithValue(*ctx, appcontext.KeyClientRequests, currentClientRequests)
-			// ignore when error occurs
-			_ = c.acknowledgeRequestService.Create(&backgroundContext, &AcknowledgeRequest{
-				RequestID:          clientRequestLog.ID,
-				CommitStatus:       "on_progress",
-				ReservedHolder:     requestRaw,
-				ReservedHolderName: reflect.TypeOf(request).Elem().Name(),
-				Message:            "",
-			})
-		}
+	if isExist == false {
+		authorizationTypes = append(authorizationTypes, authorizationType)
 	}
+	c.AuthorizationTypes = authorizationTypes
+}
 
-	if response != "" && result != nil {
-		err = json.Unmarshal([]byte(response), result)
+// NewHTTPClient creates the new http client
+func NewHTTPClient(
+	config HTTPClient,
+	clientRequestLogStorage ClientRequestLogStorage,
+	acknowledgeRequestService AcknowledgeRequestServiceInterface,
+	clientCacheService ClientCacheServiceInterface,
+	redisClient *redis.Client,
+) *HTTPClient {
+	if config.HTTPClient == nil && config.Transport != nil {
+		client, err := NewTransportClient(config.Transport)
 		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-
-		if errRedis = c.redisClient.Set(
-			fmt.Sprintf("%s:%s", "apicaching", urlPath.String()),
-			response,
-			time.Second*time.Duration(durationInSecond),
-		).Err(); err != nil {
-			log.Printf(`
-			======================================================================
-			Error Storing Caching in "CallClientWithCachingInRedis":
-			"key": %s,
-			Error: %v,
-			======================================================================
-			`, "apicaching:"+urlPath.String(), err)
+			// the calls fail with the error instead of being sent without the transport config
+			log.Printf("[HTTPClient] Error when creating the transport of %s: %v", config.ClientName, err)
+			client = &http.Client{Transport: &errorTransport{err: fmt.Errorf("invalid transport config: %v", err)}}
 		}
+		config.HTTPClient = client
 	}
-
-	return errDo
-}
-
-// CallClientWithCachingInRedisWithDifferentKey call client with caching in redis with different key
-func (c *HTTPClient) CallClientWithCachingInRedisWithDifferentKey(ctx *context.Context, durationInSecond int, path string, pathToBeStoredAsKey string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
-	var jsonData []byte
-	var err error
-	var response string
-	var errDo *ResponseError
-
-	if request != nil && request != "" {
-		jsonData, err = json.Marshal(request)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	urlPath, err := url.Parse(fmt.Sprintf("%s/%s", c.APIURL, path))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	urlPathToBeStored, err := url.Parse(fmt.Sprintf("%s/%s", c.APIURL, pathToBeStoredAsKey))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	//collect from redis if already exist
-	val, errRedis := c.redisClient.Get("apicaching:" + urlPathToBeStored.String()).Result()
-	if errRedis != nil {
-		log.Printf(`
-		======================================================================
-		Error Collecting Caching in "CallClientWithCachingInRedisWithDifferentKey":
-		"key": %s
-		Error: %v
-		======================================================================
-		`, "apicaching:"+urlPathToBeStored.String(), errRedis)
-	}
-
-	if val != "" {
-		isSuccess := true
-		if errJSON := json.Unmarshal([]byte(val), &result); errJSON != nil {
-			log.Printf(`
-			======================================================================
-			Error Collecting Caching in "CallClientWithCachingInRedisWithDifferentKey":
-			"key": %s,
-			Error: %v,
-			======================================================================
-			`, "apicaching:"+urlPathToBeStored.String(), errJSON)
-			isSuccess = false
-		}
-		if isSuccess {
-			return nil
-		}
-	}
-
-	req, err := http.NewRequest(string(method), urlPath.String(), bytes.NewBuffer(jsonData))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	for _, authorizationType := range c.AuthorizationTypes {
-		if authorizationType.HeaderType != "APIKey" {
-			req.Header.Add(authorizationType.HeaderName, fmt.Sprintf("%s%s", authorizationType.HeaderTypeValue, authorizationType.Token))
-		}
-	}
-	req.Header.Add("Content-Type", "application/json")
-
-	clientID, clientType := determineClient(ctx)
-	requestRaw := types.Metadata{}
-	if request != nil && request != "" {
-		err = json.Unmarshal(jsonData, &requestRaw)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	clientRequestLog := &ClientRequestLog{}
-	tempCurrentAccount := appcontext.CurrentAccount(ctx)
-	if tempCurrentAccount == nil {
-		defaultValue := 0
-		tempCurrentAccount = &defaultValue
-	}
-
-	command, _ := http2curl.GetCurlCommand(req)
-	requestReferenceID := appcontext.RequestReferenceID(ctx)
-	backgroundContext := context.WithValue(context.Background(), appcontext.KeyCurrentAccount, *tempCurrentAccount)
-	if method != GET {
-		clientRequestLog = c.clientRequestLogStorage.Insert(&backgroundContext, &ClientRequestLog{
-			ClientID:       clientID,
-			ClientType:     clientType,
-			Method:         string(method),
-			URL:            urlPath.String(),
-			Header:         fmt.Sprintf("%v", req.Header),
-			Request:        requestRaw,
-			Status:         "calling",
-			HTTPStatusCode: 0,
-			ReferenceID:    requestReferenceID,
-			Response:       "{}",
-			CURL:           command.String(),
-		})
-	}
-
-	response, errDo = c.Do(req)
-	if errDo != nil && (errDo.Error != nil || errDo.Message != "") {
-		if method != GET {
-			clientRequestLog.HTTPStatusCode = errDo.StatusCode
-			clientRequestLog.Status = "failed"
-			clientRequestLog.Response = response
-			clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-		}
-		return errDo
-	}
-
-	type TransactionID struct {
-		ID int `json:"id"`
-	}
-	var transactionID TransactionID
-	json.Unmarshal([]byte(response), &transactionID)
-
-	if method != GET {
-		clientRequestLog.TransactionID = transactionID.ID
-		if errDo != nil {
-			clientRequestLog.HTTPStatusCode = errDo.StatusCode
-		}
-		clientRequestLog.Status = "success"
-		clientRequestLog.Response = response
-		clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-
-		requestStatus := appcontext.RequestStatus(ctx)
-		if requestStatus == nil && isAcknowledgeNeeded {
-			currentClientRequests := []*ClientRequest{}
-			temp := appcontext.ClientRequests(ctx)
-			if temp != nil {
-				currentClientRequests = temp.([]*ClientRequest)
-			}
-			currentClientRequests = append(currentClientRequests, &ClientRequest{
-				Client:  c,
-				Request: clientRequestLog,
-			})
-			*ctx = context.WithValue(*ctx, appcontext.KeyClientRequests, currentClientRequests)
-			// ignore when error occurs
-			_ = c.acknowledgeRequestService.Create(&backgroundContext, &AcknowledgeRequest{
-				RequestID:          clientRequestLog.ID,
-				CommitStatus:       "on_progress",
-				ReservedHolder:     requestRaw,
-				ReservedHolderName: reflect.TypeOf(request).Elem().Name(),
-				Message:            "",
-			})
-		}
-	}
-
-	if response != "" && result != nil {
-		err = json.Unmarshal([]byte(response), result)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-
-		if errRedis = c.redisClient.Set(
-			fmt.Sprintf("%s:%s", "apicaching", urlPathToBeStored.String()),
-			response,
-			time.Second*time.Duration(durationInSecond),
-		).Err(); err != nil {
-			log.Printf(`
-			======================================================================
-			Error Storing Caching in "CallClientWithCachingInRedisWithDifferentKey":
-			"key": %s,
-			Error: %v,
-			======================================================================
-			`, "apicaching:"+urlPathToBeStored.String(), err)
-		}
-	}
-
-	return errDo
-}
-
-// CallClientWithCircuitBreaker do call client with circuit breaker (async)
-func (c *HTTPClient) CallClientWithCircuitBreaker(ctx *context.Context, path string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
-	var jsonData []byte
-	var err error
-	var response string
-	var errDo *ResponseError
-
-	Sethystrix(c.ClientName)
-	err = hystrix.Do(c.ClientName, func() error {
-		if request != nil {
-			jsonData, err = json.Marshal(request)
-			if err != nil {
-				errDo = &ResponseError{
-					Error: err,
-				}
-				return errDo.Error
-			}
-		}
-
-		urlPath, err := url.Parse(fmt.Sprintf("%s/%s", c.APIURL, path))
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo.Error
-		}
-
-		req, err := http.NewRequest(string(method), urlPath.String(), bytes.NewBuffer(jsonData))
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo.Error
-		}
-
-		for _, authorizationType := range c.AuthorizationTypes {
-			if authorizationType.HeaderType != "APIKey" {
-				req.Header.Add(authorizationType.HeaderName, fmt.Sprintf("%s%s", authorizationType.HeaderTypeValue, authorizationType.Token))
-			}
-		}
-		req.Header.Add("Content-Type", "application/json")
-
-		clientID, clientType := determineClient(ctx)
-		requestRaw := types.Metadata{}
-		if request != nil {
-			err = json.Unmarshal(jsonData, &requestRaw)
-			if err != nil {
-				errDo = &ResponseError{
-					Error: err,
-				}
-				return errDo.Error
-			}
-		}
-
-		clientRequestLog := &ClientRequestLog{}
-		tempCurrentAccount := appcontext.CurrentAccount(ctx)
-		if tempCurrentAccount == nil {
-			defaultValue := 0
-			tempCurrentAccount = &defaultValue
-		}
-
-		command, _ := http2curl.GetCurlCommand(req)
-		requestReferenceID := appcontext.RequestReferenceID(ctx)
-		backgroundContext := context.WithValue(context.Background(), appcontext.KeyCurrentAccount, *tempCurrentAccount)
-		if method != GET {
-			clientRequestLog = c.clientRequestLogStorage.Insert(&backgroundContext, &ClientRequestLog{
-				ClientID:       clientID,
-				ClientType:     clientType,
-				Method:         string(method),
-				URL:            urlPath.String(),
-				Header:         fmt.Sprintf("%v", req.Header),
-				Request:        requestRaw,
-				Status:         "calling",
-				HTTPStatusCode: 0,
-				ReferenceID:    requestReferenceID,
-				Response:       "{}",
-				CURL:           command.String(),
-			})
-		}
-
-		response, errDo = c.Do(req)
-		if errDo != nil && (errDo.Error != nil || errDo.Message != "") {
-			if method != GET {
-				clientRequestLog.HTTPStatusCode = errDo.StatusCode
-				clientRequestLog.Status = "failed"
-				clientRequestLog.Response = response
-				clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-			}
-
-			return errDo.Error
-		}
-
-		type TransactionID struct {
-			ID int `json:"id"`
-		}
-		var transactionID TransactionID
-		json.Unmarshal([]byte(response), &transactionID)
-
-		if method != GET {
-			clientRequestLog.TransactionID = transactionID.ID
-			if errDo != nil {
-				clientRequestLog.HTTPStatusCode = errDo.StatusCode
-			}
-			clientRequestLog.Status = "success"
-			clientRequestLog.Response = response
-			clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-
-			requestStatus := appcontext.RequestStatus(ctx)
-			if requestStatus == nil && isAcknowledgeNeeded {
-				currentClientRequests := []*ClientRequest{}
-				temp := appcontext.ClientRequests(ctx)
-				if temp != nil {
-					currentClientRequests = temp.([]*ClientRequest)
-				}
-				currentClientRequests = append(currentClientRequests, &ClientRequest{
-					Client:  c,
-					Request: clientRequestLog,
-				})
-				*ctx = context.WithValue(*ctx, appcontext.KeyClientRequests, currentClientRequests)
-				// ignore when error occurs
-				_ = c.acknowledgeRequestService.Create(&backgroundContext, &AcknowledgeRequest{
-					RequestID:          clientRequestLog.ID,
-					CommitStatus:       "on_progress",
-					ReservedHolder:     requestRaw,
-					ReservedHolderName: reflect.TypeOf(request).Elem().Name(),
-					Message:            "",
-				})
-			}
-		}
-
-		if response != "" && result != nil {
-			err = json.Unmarshal([]byte(response), result)
-			if err != nil {
-				errDo = &ResponseError{
-					Error: err,
-				}
-				return errDo.Error
-			}
-		}
-		return nil
-	}, nil)
-
-	return errDo
-}
-
-// CallClientWithoutLog do call client without log
-func (c *HTTPClient) CallClientWithoutLog(ctx *context.Context, path string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
-	var jsonData []byte
-	var err error
-	var response string
-	var errDo *ResponseError
-
-	if request != nil && request != "" {
-		jsonData, err = json.Marshal(request)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	urlPath, err := url.Parse(fmt.Sprintf("%s/%s", c.APIURL, path))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	req, err := http.NewRequest(string(method), urlPath.String(), bytes.NewBuffer(jsonData))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	for _, authorizationType := range c.AuthorizationTypes {
-		if authorizationType.HeaderType != "APIKey" {
-			req.Header.Add(authorizationType.HeaderName, fmt.Sprintf("%s%s", authorizationType.HeaderTypeValue, authorizationType.Token))
-		}
-	}
-	req.Header.Add("Content-Type", "application/json")
-
-	response, errDo = c.Do(req)
-	if errDo != nil && (errDo.Error != nil || errDo.Message != "") {
-		return errDo
-	}
-
-	if response != "" && result != nil {
-		err = json.Unmarshal([]byte(response), result)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	return errDo
-}
-
-// CallClientWithBaseURLGiven do call client with base url given
-func (c *HTTPClient) CallClientWithBaseURLGiven(ctx *context.Context, url string, method Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
-	var jsonData []byte
-	var err error
-	var response string
-	var errDo *ResponseError
-
-	if request != nil && request != "" {
-		jsonData, err = json.Marshal(request)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	req, err := http.NewRequest(string(method), url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	for _, authorizationType := range c.AuthorizationTypes {
-		if authorizationType.HeaderType != "APIKey" {
-			req.Header.Add(authorizationType.HeaderName, fmt.Sprintf("%s%s", authorizationType.HeaderTypeValue, authorizationType.Token))
-		}
-	}
-	req.Header.Add("Content-Type", "application/json")
-
-	response, errDo = c.Do(req)
-	if errDo != nil && (errDo.Error != nil || errDo.Message != "") {
-		return errDo
-	}
-
-	if response != "" && result != nil {
-		err = json.Unmarshal([]byte(response), result)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	return errDo
-}
-
-// CallClientWithCustomizedError do call client with customized error
-func (c *HTTPClient) CallClientWithCustomizedError(ctx *context.Context, path string, method Method, queryParams interface{}, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
-	var jsonData []byte
-	var err error
-	var errDo *ResponseError
-
-	if request != nil {
-		jsonData, err = json.Marshal(request)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	for _, authorizationType := range c.AuthorizationTypes {
-		if authorizationType.HeaderName == "APIKey" {
-			s := reflect.ValueOf(queryParams).Elem()
-			field := s.FieldByName("APIKey")
-			if field.IsValid() {
-				field.SetString(authorizationType.Token)
-				path = ParseQueryParams(path, queryParams)
-			}
-		}
-	}
-
-	urlPath, err := url.ParseRequestURI(fmt.Sprintf("%s/%s", c.APIURL, path))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	jsonBuffer := bytes.NewBuffer(jsonData)
-	req, err := http.NewRequest(string(method), urlPath.String(), jsonBuffer)
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	for _, authorizationType := range c.AuthorizationTypes {
-		if authorizationType.HeaderType != "APIKey" {
-			req.Header.Add(authorizationType.HeaderName, fmt.Sprintf("%s%s", authorizationType.HeaderTypeValue, authorizationType.Token))
-		}
-	}
-	req.Header.Add("Content-Type", "application/json")
-
-	clientID, clientType := determineClient(ctx)
-	requestRaw := types.Metadata{}
-	if request != nil {
-		err = json.Unmarshal(jsonData, &requestRaw)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	clientRequestLog := &ClientRequestLog{}
-	tempCurrentAccount := appcontext.CurrentAccount(ctx)
-	if tempCurrentAccount == nil {
-		defaultValue := 0
-		tempCurrentAccount = &defaultValue
-	}
-
-	command, _ := http2curl.GetCurlCommand(req)
-	requestReferenceID := appcontext.RequestReferenceID(ctx)
-	backgroundContext := context.WithValue(context.Background(), appcontext.KeyCurrentAccount, *tempCurrentAccount)
-	clientRequestLog = c.clientRequestLogStorage.Insert(&backgroundContext, &ClientRequestLog{
-		ClientID:       clientID,
-		ClientType:     clientType,
-		Method:         string(method),
-		URL:            urlPath.String(),
-		Header:         fmt.Sprintf("%v", req.Header),
-		Request:        requestRaw,
-		Status:         "calling",
-		HTTPStatusCode: 0,
-		ReferenceID:    requestReferenceID,
-		Response:       "{}",
-		CURL:           command.String(),
-	})
-
-	response, errDo := (func() (string, *ResponseError) {
-		var res *http.Response
-		var err error
-		for retry := 0; ; {
-			res, err = c.HTTPClient.Do(req)
-			if !c.shouldRetry(err, res, retry) {
-				break
-			}
-			sleepDuration := c.sleepTime(retry)
-			retry++
-			time.Sleep(sleepDuration)
-		}
-		if err != nil {
-			return "", &ResponseError{
-				Code:    "",
-				Message: "",
-				Fields:  nil,
-				Error:   err,
-			}
-		}
-		defer res.Body.Close()
-
-		resBody, err := ioutil.ReadAll(res.Body)
-		res.Body.Close()
-		if err != nil {
-			return "", &ResponseError{
-				Code:       string(res.StatusCode),
-				Message:    "",
-				Fields:     nil,
-				StatusCode: res.StatusCode,
-				Error:      err,
-			}
-		}
-
-		errResponse := &ResponseError{
-			Code:       string(res.StatusCode),
-			Message:    "",
-			Fields:     nil,
-			StatusCode: res.StatusCode,
-			Error:      nil,
-		}
-		if res.StatusCode < 200 || res.StatusCode >= 300 {
-			errResponse.Message = string(resBody)
-			errResponse.Error = errors.New(string(resBody))
-
-			return "", errResponse
-		}
-
-		return string(resBody), errResponse
-	})()
-	if errDo != nil && (errDo.Error != nil || errDo.Message != "") {
-		clientRequestLog.HTTPStatusCode = errDo.StatusCode
-		clientRequestLog.Status = "failed"
-		clientRequestLog.Response = response
-		clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-
-		return errDo
-	}
-
-	type TransactionID struct {
-		ID int `json:"id"`
-	}
-	var transactionID TransactionID
-	json.Unmarshal([]byte(response), &transactionID)
-
-	if method != GET {
-		clientRequestLog.TransactionID = transactionID.ID
-		clientRequestLog.Response = response
-	}
-	if errDo != nil {
-		clientRequestLog.HTTPStatusCode = errDo.StatusCode
-	}
-	clientRequestLog.Status = "success"
-	clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-
-	requestStatus := appcontext.RequestStatus(ctx)
-	if requestStatus == nil && isAcknowledgeNeeded {
-		currentClientRequests := []*ClientRequest{}
-		temp := appcontext.ClientRequests(ctx)
-		if temp != nil {
-			currentClientRequests = temp.([]*ClientRequest)
-		}
-		currentClientRequests = append(currentClientRequests, &ClientRequest{
-			Client:  c,
-			Request: clientRequestLog,
-		})
-		*ctx = context.WithValue(*ctx, appcontext.KeyClientRequests, currentClientRequests)
-		// ignore when error occurs
-		_ = c.acknowledgeRequestService.Create(&backgroundContext, &AcknowledgeRequest{
-			RequestID:          clientRequestLog.ID,
-			CommitStatus:       "on_progress",
-			ReservedHolder:     requestRaw,
-			ReservedHolderName: reflect.TypeOf(request).Elem().Name(),
-			Message:            "",
-		})
-	}
-
-	if response != "" && result != nil {
-		err = json.Unmarshal([]byte(response), result)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	return errDo
-}
-
-// CallClientWithCustomizedErrorAndCaching do call client with customized error and caching
-func (c *HTTPClient) CallClientWithCustomizedErrorAndCaching(ctx *context.Context, path string, method Method, queryParams interface{}, request interface{}, result interface{}, isAcknowledgeNeeded bool) *ResponseError {
-	var jsonData []byte
-	var err error
-	var errDo *ResponseError
-
-	if request != nil && request != "" {
-		jsonData, err = json.Marshal(request)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	for _, authorizationType := range c.AuthorizationTypes {
-		if authorizationType.HeaderName == "APIKey" {
-			s := reflect.ValueOf(queryParams).Elem()
-			field := s.FieldByName("APIKey")
-			if field.IsValid() {
-				field.SetString(authorizationType.Token)
-				path = ParseQueryParams(path, queryParams)
-			}
-		}
-	}
-
-	urlPath, err := url.Parse(fmt.Sprintf("%s/%s", c.APIURL, path))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	req, err := http.NewRequest(string(method), urlPath.String(), bytes.NewBuffer(jsonData))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	for _, authorizationType := range c.AuthorizationTypes {
-		if authorizationType.HeaderType != "APIKey" {
-			req.Header.Add(authorizationType.HeaderName, fmt.Sprintf("%s%s", authorizationType.HeaderTypeValue, authorizationType.Token))
-		}
-	}
-	req.Header.Add("Content-Type", "application/json")
-
-	clientID, clientType := determineClient(ctx)
-	requestRaw := types.Metadata{}
-	if request != nil && request != "" {
-		err = json.Unmarshal(jsonData, &requestRaw)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	clientRequestLog := &ClientRequestLog{}
-	tempCurrentAccount := appcontext.CurrentAccount(ctx)
-	if tempCurrentAccount == nil {
-		defaultValue := 0
-		tempCurrentAccount = &defaultValue
-	}
-
-	command, _ := http2curl.GetCurlCommand(req)
-	requestReferenceID := appcontext.RequestReferenceID(ctx)
-	backgroundContext := context.WithValue(context.Background(), appcontext.KeyCurrentAccount, *tempCurrentAccount)
-	clientRequestLog = c.clientRequestLogStorage.Insert(&backgroundContext, &ClientRequestLog{
-		ClientID:       clientID,
-		ClientType:     clientType,
-		Method:         string(method),
-		URL:            urlPath.String(),
-		Header:         fmt.Sprintf("%v", req.Header),
-		Request:        requestRaw,
-		Status:         "calling",
-		HTTPStatusCode: 0,
-		ReferenceID:    requestReferenceID,
-		Response:       "{}",
-		CURL:           command.String(),
-	})
-
-	isAllowed, errClientCache := c.clientCacheService.IsClientNeedToBeCache(ctx, urlPath.String(), string(method))
-	if errClientCache != nil {
-		fmt.Printf("\nFailed to IsClientNeedToBeCache while collecting caching information: %v", errClientCache)
-	}
-
-	isError := false
-	response, errDo := (func() (string, *ResponseError) {
-		var res *http.Response
-		var err error
-		for retry := 0; ; {
-			res, err = c.HTTPClient.Do(req)
-			if !c.shouldRetry(err, res, retry) {
-				break
-			}
-			sleepDuration := c.sleepTime(retry)
-			retry++
-			time.Sleep(sleepDuration)
-		}
-		if err != nil {
-			return "", &ResponseError{
-				Code:    "",
-				Message: "",
-				Fields:  nil,
-				Error:   err,
-			}
-		}
-		defer res.Body.Close()
-
-		resBody, err := ioutil.ReadAll(res.Body)
-		res.Body.Close()
-		if err != nil {
-			return "", &ResponseError{
-				Code:       string(res.StatusCode),
-				Message:    "",
-				Fields:     nil,
-				StatusCode: res.StatusCode,
-				Error:      err,
-			}
-		}
-
-		errResponse := &ResponseError{
-			Code:       string(res.StatusCode),
-			Message:    "",
-			Fields:     nil,
-			StatusCode: res.StatusCode,
-			Error:      nil,
-		}
-		if res.StatusCode < 200 || res.StatusCode >= 300 {
-			errResponse.Message = string(resBody)
-			errResponse.Error = errors.New(string(resBody))
-			return "", errResponse
-		}
-
-		return string(resBody), errResponse
-	})()
-	if errDo != nil && (errDo.Error != nil || errDo.Message != "") {
-		clientRequestLog.HTTPStatusCode = errDo.StatusCode
-		clientRequestLog.Status = "failed"
-		clientRequestLog.Response = response
-		clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-
-		// Do check cache
-		isError = true
-		if !isAllowed {
-			return errDo
-		}
-
-		isSuccessCollectingCache := true
-
-		// collect cache
-		clientCache, errClientCache := c.clientCacheService.GetClientCacheByURL(ctx, &GetClientCacheByURLParams{
-			URL:      urlPath.String(),
-			Method:   string(method),
-			IsActive: true,
-		})
-		if errClientCache != nil {
-			fmt.Printf("\nFailed to GetClientCacheByURL while collecting caching: %v", errClientCache)
-			fmt.Printf("\n\tParams: %#v", GetClientCacheByURLParams{
-				URL:      urlPath.String(),
-				Method:   string(method),
-				IsActive: false,
-			})
-			isSuccessCollectingCache = false
-		}
-
-		var bytes []byte
-		var errJSON error
-		if isSuccessCollectingCache {
-			bytes, errJSON = json.Marshal(clientCache.Response)
-			if errJSON != nil {
-				fmt.Printf("\nFailed to json.Marshal to convert cached response while doing collecting caching data: %v", errJSON)
-				isSuccessCollectingCache = false
-			}
-		}
-
-		if isSuccessCollectingCache {
-			fmt.Printf("\n\n============================================================\n")
-			fmt.Printf("\nFailed to call client: %#v\n", errDo)
-			fmt.Printf("\n\tSuccess collecting last cached and omitting error client\n")
-			fmt.Printf("\n============================================================\n\n")
-			errDo = nil
-		}
-		response = string(bytes)
-	}
-
-	if isAllowed && !isError {
-		// do caching
-		isExist := true
-		currentClientCache, errClientCache := c.clientCacheService.GetClientCacheByURL(ctx, &GetClientCacheByURLParams{
-			URL:      urlPath.String(),
-			Method:   string(method),
-			IsActive: false,
-		})
-		if errClientCache != nil {
-			if errClientCache.Message != "data is not found" {
-				fmt.Printf("\nFailed to GetClientCacheByURL while collecting caching in order to update cache: %v", errClientCache)
-				fmt.Printf("\n\tParams: %#v", GetClientCacheByURLParams{
-					URL:      urlPath.String(),
-					Method:   string(method),
-					IsActive: false,
-				})
-			}
-			isExist = false
-		}
-
-		responseInMap := types.Metadata{}
-		if response != "" {
-			errJSON := json.Unmarshal([]byte(response), &responseInMap)
-			if errJSON != nil {
-				fmt.Printf("\nFailed to json.Unmarshal to convert response while doing caching: %v", errJSON)
-			}
-		}
-
-		if isExist {
-			// update cache
-			currentClientCache.Response = types.Metadata{}
-			currentClientCache.Response = responseInMap
-			currentClientCache.LastAccessed = time.Now().UTC()
-
-			_, errClientCache = c.clientCacheService.UpdateClientCache(ctx, currentClientCache.ID, &UpdateClientCacheParams{
-				URL:          currentClientCache.URL,
-				Method:       currentClientCache.Method,
-				ClientID:     currentClientCache.ClientID,
-				ClientName:   currentClientCache.ClientName,
-				Response:     currentClientCache.Response,
-				LastAccessed: currentClientCache.LastAccessed,
-			})
-			if errClientCache != nil {
-				fmt.Printf("\nFailed to UpdateClientCache while doing caching: %v", errClientCache)
-			}
-		} else {
-			// create new cache
-			tempClientID := appcontext.ClientID(ctx)
-			clientID := 0
-			if tempClientID != nil {
-				clientID = *tempClientID
-			}
-
-			_, errClientCache = c.clientCacheService.CreateClientCache(ctx, &CreateClientCacheParams{
-				URL:          urlPath.String(),
-				Method:       string(method),
-				ClientID:     clientID,
-				ClientName:   c.ClientName,
-				Response:     responseInMap,
-				LastAccessed: time.Now().UTC(),
-			})
-			if errClientCache != nil {
-				fmt.Printf("\nFailed to CreateClientCache while doing caching: %v", errClientCache)
-			}
-		}
-	}
-
-	type TransactionID struct {
-		ID int `json:"id"`
-	}
-	var transactionID TransactionID
-	json.Unmarshal([]byte(response), &transactionID)
-
-	if method != GET {
-		clientRequestLog.TransactionID = transactionID.ID
-		clientRequestLog.Response = response
-	}
-	if errDo != nil {
-		clientRequestLog.HTTPStatusCode = errDo.StatusCode
-	}
-	clientRequestLog.Status = "success"
-	clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-
-	requestStatus := appcontext.RequestStatus(ctx)
-	if requestStatus == nil && isAcknowledgeNeeded {
-		currentClientRequests := []*ClientRequest{}
-		temp := appcontext.ClientRequests(ctx)
-		if temp != nil {
-			currentClientRequests = temp.([]*ClientRequest)
-		}
-		currentClientRequests = append(currentClientRequests, &ClientRequest{
-			Client:  c,
-			Request: clientRequestLog,
-		})
-		*ctx = context.WithValue(*ctx, appcontext.KeyClientRequests, currentClientRequests)
-		// ignore when error occurs
-		_ = c.acknowledgeRequestService.Create(&backgroundContext, &AcknowledgeRequest{
-			RequestID:          clientRequestLog.ID,
-			CommitStatus:       "on_progress",
-			ReservedHolder:     requestRaw,
-			ReservedHolderName: reflect.TypeOf(request).Elem().Name(),
-			Message:            "",
-		})
-	}
-
-	if response != "" && result != nil {
-		err = json.Unmarshal([]byte(response), result)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	return errDo
-}
-
-// CallClientWithRequestInBytes do call client with request in bytes and omit acknowledge process - specific case for consumer
-func (c *HTTPClient) CallClientWithRequestInBytes(ctx *context.Context, path string, method Method, request []byte, result interface{}) *ResponseError {
-	var jsonData []byte = request
-	var err error
-	var response string
-	var errDo *ResponseError
-
-	urlPath, err := url.Parse(fmt.Sprintf("%s/%s", c.APIURL, path))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	req, err := http.NewRequest(string(method), urlPath.String(), bytes.NewBuffer(jsonData))
-	if err != nil {
-		errDo = &ResponseError{
-			Error: err,
-		}
-		return errDo
-	}
-
-	for _, authorizationType := range c.AuthorizationTypes {
-		if authorizationType.HeaderType != "APIKey" {
-			req.Header.Add(authorizationType.HeaderName, fmt.Sprintf("%s%s", authorizationType.HeaderTypeValue, authorizationType.Token))
-		}
-	}
-	req.Header.Add("Content-Type", "application/json")
-
-	clientID, clientType := determineClient(ctx)
-	requestRaw := types.Metadata{}
-	if request != nil && len(request) != 0 {
-		err = json.Unmarshal(jsonData, &requestRaw)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	clientRequestLog := &ClientRequestLog{}
-	tempCurrentAccount := appcontext.CurrentAccount(ctx)
-	if tempCurrentAccount == nil {
-		defaultValue := 0
-		tempCurrentAccount = &defaultValue
-	}
-	command, _ := http2curl.GetCurlCommand(req)
-
-	requestReferenceID := appcontext.RequestReferenceID(ctx)
-	backgroundContext := context.WithValue(context.Background(), appcontext.KeyCurrentAccount, *tempCurrentAccount)
-	if method != GET {
-		clientRequestLog = c.clientRequestLogStorage.Insert(&backgroundContext, &ClientRequestLog{
-			ClientID:       clientID,
-			ClientType:     clientType,
-			Method:         string(method),
-			URL:            urlPath.String(),
-			Header:         fmt.Sprintf("%v", req.Header),
-			Request:        requestRaw,
-			Status:         "calling",
-			HTTPStatusCode: 0,
-			ReferenceID:    requestReferenceID,
-			Response:       "{}",
-			CURL:           command.String(),
-		})
-	}
-
-	response, errDo = c.Do(req)
-	if errDo != nil && (errDo.Error != nil || errDo.Message != "") {
-		if method != GET {
-			clientRequestLog.HTTPStatusCode = errDo.StatusCode
-			clientRequestLog.Status = "failed"
-			clientRequestLog.Response = response
-			clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-		}
-		return errDo
-	}
-
-	type TransactionID struct {
-		ID int `json:"id"`
-	}
-	var transactionID TransactionID
-	json.Unmarshal([]byte(response), &transactionID)
-
-	if method != GET {
-		clientRequestLog.TransactionID = transactionID.ID
-		if errDo != nil {
-			clientRequestLog.HTTPStatusCode = errDo.StatusCode
-		}
-		clientRequestLog.Status = "success"
-		clientRequestLog.Response = response
-		clientRequestLog = c.clientRequestLogStorage.Update(&backgroundContext, clientRequestLog)
-	}
-
-	if response != "" && result != nil {
-		err = json.Unmarshal([]byte(response), result)
-		if err != nil {
-			errDo = &ResponseError{
-				Error: err,
-			}
-			return errDo
-		}
-	}
-
-	return errDo
-}
-
-// AddAuthentication do add authentication
-func (c *HTTPClient) AddAuthentication(ctx *context.Context, authorizationType AuthorizationType) {
-	isExist := false
-	for key, singleAuthorizationType := range c.AuthorizationTypes {
-		if singleAuthorizationType.HeaderType == authorizationType.HeaderType {
-			c.AuthorizationTypes[key].Token = authorizationType.Token
-			isExist = true
-			break
-		}
-	}
-
-	if isExist == false {
-		c.AuthorizationTypes = append(c.AuthorizationTypes, authorizationType)
-	}
-}
-
-// NewHTTPClient creates the new http client
-func NewHTTPClient(
-	config HTTPClient,
-	clientRequestLogStorage ClientRequestLogStorage,
-	acknowledgeRequestService AcknowledgeRequestServiceInterface,
-	clientCacheService ClientCacheServiceInterface,
-	redisClient *redis.Client,
-) *HTTPClient {
 	if config.HTTPClient == nil {
 		config.HTTPClient = httpClient
 	}
@@ -1895,11 +406,19 @@ func NewHTTPClient(
 		acknowledgeRequestService: acknowledgeRequestService,
 		clientCacheService:        clientCacheService,
 		ClientName:                config.ClientName,
+		RetryPolicy:               config.RetryPolicy,
+		BreakerConfig:             config.BreakerConfig,
+		RateLimitConfig:           config.RateLimitConfig,
+		AuthProvider:              config.AuthProvider,
+		RequestSigner:             config.RequestSigner,
+		Transport:                 config.Transport,
+		RedactionPolicy:           config.RedactionPolicy,
+		ErrorDecoder:              config.ErrorDecoder,
 		redisClient:               redisClient,
 	}
 }
 
-// Sethystrix setting for client
+// Sethystrix setting for client, the calls of WithCircuitBreaker are configured by BreakerConfig instead
 func Sethystrix(nameClient string) {
 	hystrix.ConfigureCommand(nameClient, hystrix.CommandConfig{
 		Timeout:               5000,
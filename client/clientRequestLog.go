@@ -2,14 +2,18 @@ package client
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/payfazz/commerce-kit/types"
 )
 
-// ClientRequest encapsulated object of http client and client request log for acknowledge used
+// ClientRequest encapsulated object of http client and client request log for acknowledge used,
+// the acknowledge replays the encoded Body with its ContentType, or the logged request as json when it's nil
 type ClientRequest struct {
-	Client  *HTTPClient
-	Request *ClientRequestLog
+	Client      *HTTPClient
+	Request     *ClientRequestLog
+	Body        []byte
+	ContentType string
 }
 
 // ClientRequestLog object of client request log (log of request to external client)
@@ -28,6 +32,14 @@ type ClientRequestLog struct {
 	ReferenceID    int            `json:"referenceId" db:"referenceId"`
 	Response       string         `json:"response" db:"response"`
 	CURL           string         `json:"curl" db:"curl"`
+	// Metadata holds the attempts & the stream metadata, its column is added by ClientRequestLogMetadataQuery
+	Metadata types.Metadata `json:"metadata" db:"metadata"`
+}
+
+// ClientRequestLogMetadataQuery returns the query to add the metadata column into the client request log table,
+// it's meant to be used in the migration
+func ClientRequestLogMetadataQuery(tableName string) string {
+	return fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN IF NOT EXISTS "metadata" JSONB NOT NULL DEFAULT '{}'`, tableName)
 }
 
 // FindAllClientRequestLogs represents params to get All Client Request Logs
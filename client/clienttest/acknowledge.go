@@ -0,0 +1,102 @@
+package clienttest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/payfazz/commerce-kit/appcontext"
+	"github.com/payfazz/commerce-kit/client"
+)
+
+// Acknowledgement represents a commit or rollback acknowledged by the AcknowledgeService
+type Acknowledgement struct {
+	Status  string
+	Message string
+	// Call is the call of the fake clients acknowledged, it's nil for the requests of the other clients
+	Call    *Call
+	Request *client.ClientRequestLog
+}
+
+// AcknowledgeService is the fake client.AcknowledgeRequestServiceInterface acknowledging the calls of the fake clients,
+// it's given to data.NewManager so the commit & rollback of RunInTransaction can be asserted
+type AcknowledgeService struct {
+	mu               sync.Mutex
+	clients          []*Client
+	acknowledgements []*Acknowledgement
+	created          []*client.AcknowledgeRequest
+}
+
+// NewAcknowledgeService creates the fake acknowledge service of the fake clients
+func NewAcknowledgeService(clients ...*Client) *AcknowledgeService {
+	return &AcknowledgeService{
+		clients: clients,
+	}
+}
+
+// Prepare does nothing, the request isn't logged
+func (s *AcknowledgeService) Prepare(ctx *context.Context) error {
+	return nil
+}
+
+// Create captures the acknowledge request
+func (s *AcknowledgeService) Create(ctx *context.Context, acknowledgeRequest *client.AcknowledgeRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.created = append(s.created, acknowledgeRequest)
+	return nil
+}
+
+// Acknowledge sets the request status of the context and marks the calls registered in the context with the status
+func (s *AcknowledgeService) Acknowledge(ctx *context.Context, status string, message string) error {
+	*ctx = context.WithValue(*ctx, appcontext.KeyRequestStatus, status)
+	clientRequests := []*client.ClientRequest{}
+	temp := appcontext.ClientRequests(ctx)
+	if temp != nil {
+		clientRequests = temp.([]*client.ClientRequest)
+	}
+
+	for _, clientRequest := range clientRequests {
+		var call *Call
+		for _, c := range s.clients {
+			if call = c.callByLog(clientRequest.Request); call != nil {
+				c.mu.Lock()
+				call.AcknowledgeStatus = status
+				c.mu.Unlock()
+				break
+			}
+		}
+
+		s.mu.Lock()
+		s.acknowledgements = append(s.acknowledgements, &Acknowledgement{
+			Status:  status,
+			Message: message,
+			Call:    call,
+			Request: clientRequest.Request,
+		})
+		s.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Acknowledgements returns the acknowledged requests
+func (s *AcknowledgeService) Acknowledgements() []*Acknowledgement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Acknowledgement{}, s.acknowledgements...)
+}
+
+// AcknowledgeRequests returns the acknowledge requests created
+func (s *AcknowledgeService) AcknowledgeRequests() []*client.AcknowledgeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*client.AcknowledgeRequest{}, s.created...)
+}
+
+// Reset clears the acknowledgements & the acknowledge requests
+func (s *AcknowledgeService) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acknowledgements = nil
+	s.created = nil
+}
@@ -0,0 +1,101 @@
+package clienttest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/payfazz/commerce-kit/client"
+	"github.com/payfazz/commerce-kit/data"
+)
+
+// fakeDriver opens the connections whose transactions only record the commit or rollback
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake connection doesn't run queries")
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+func init() {
+	sql.Register("clienttest-fake", fakeDriver{})
+}
+
+func TestRunInTransactionAcknowledge(t *testing.T) {
+	db, err := sql.Open("clienttest-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		err     error
+		status  string
+		message string
+	}{
+		{"commit", nil, "commit", ""},
+		{"rollback", errors.New("out of stock"), "rollback", "out of stock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewClient()
+			fake.Expect(client.POST, "/reservations").Return(map[string]interface{}{"id": 7}, nil)
+			fake.Expect(client.GET, "/stock").Return(map[string]interface{}{"qty": 1}, nil)
+			acknowledgeService := NewAcknowledgeService(fake)
+			manager := data.NewManager(sqlx.NewDb(db, "postgres"), acknowledgeService, nil)
+
+			ctx := context.Background()
+			err := manager.RunInTransaction(&ctx, func(tctx *context.Context) error {
+				fake.CallClient(tctx, "/reservations", client.POST, map[string]interface{}{"qty": 1}, nil, true)
+				fake.CallClient(tctx, "/stock", client.GET, nil, nil, false)
+				return tt.err
+			})
+			if err != tt.err {
+				t.Fatalf("RunInTransaction() error = %v, want %v", err, tt.err)
+			}
+
+			acknowledgements := acknowledgeService.Acknowledgements()
+			if len(acknowledgements) != 1 || acknowledgements[0].Status != tt.status || acknowledgements[0].Message != tt.message {
+				t.Fatalf("Acknowledgements() = %+v, want the reservation acknowledged with %s", acknowledgements, tt.status)
+			}
+			if acknowledgements[0].Call != fake.CallsTo(client.POST, "/reservations")[0] {
+				t.Errorf("Acknowledgements() call = %+v, want the reservation call", acknowledgements[0].Call)
+			}
+			if status := fake.CallsTo(client.POST, "/reservations")[0].AcknowledgeStatus; status != tt.status {
+				t.Errorf("reservation acknowledge status = %q, want %q", status, tt.status)
+			}
+			if status := fake.CallsTo(client.GET, "/stock")[0].AcknowledgeStatus; status != "" {
+				t.Errorf("stock acknowledge status = %q, want the call without acknowledge left alone", status)
+			}
+			fake.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,468 @@
+// Package clienttest provides the fake client.Caller with expectations for the tests of the services calling clients
+package clienttest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/payfazz/commerce-kit/appcontext"
+	"github.com/payfazz/commerce-kit/client"
+	"github.com/payfazz/commerce-kit/types"
+)
+
+// ErrUnexpectedCall is returned when the call doesn't match any expectation
+var ErrUnexpectedCall = errors.New("unexpected call")
+
+// baseURL is the base url of the logged calls, the acknowledge of client.AcknowledgeRequestService is sent to it
+const baseURL = "http://clienttest"
+
+// Call represents a call captured by the fake client
+type Call struct {
+	// Variant is the name of the method called, e.g. CallClient or Call
+	Variant             string
+	Method              client.Method
+	Path                string
+	Request             interface{}
+	Body                types.Metadata
+	QueryParams         interface{}
+	Header              http.Header
+	IsAcknowledgeNeeded bool
+	// Log is the client request log registered in the context when the call is acknowledged
+	Log *client.ClientRequestLog
+	// AcknowledgeStatus is the commit or rollback status acknowledged by the AcknowledgeService
+	// or client.AcknowledgeRequestService
+	AcknowledgeStatus string
+	Error             *client.ResponseError
+}
+
+// Expectation represents an expected call with its result
+type Expectation struct {
+	method  client.Method
+	path    string
+	body    interface{}
+	hasBody bool
+	result  interface{}
+	err     *client.ResponseError
+	times   int
+	calls   int
+}
+
+// WithBody expects the request body equals to the json representation of the body
+func (e *Expectation) WithBody(body interface{}) *Expectation {
+	e.body = body
+	e.hasBody = true
+	return e
+}
+
+// Return sets the result parsed into the result of the call and the error returned, the string result is the raw response
+func (e *Expectation) Return(result interface{}, err *client.ResponseError) *Expectation {
+	e.result = result
+	e.err = err
+	return e
+}
+
+// Times expects the call to be called n times, the expectation is matched any times but at least once by default
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Once expects the call to be called once
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+func (e *Expectation) String() string {
+	return fmt.Sprintf("%s %s", e.method, e.path)
+}
+
+// matchPath matches the path given or its glob pattern, the leading slashes are ignored
+func matchPath(pattern string, value string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	value = strings.TrimPrefix(value, "/")
+	if pattern == value {
+		return true
+	}
+	isMatch, err := path.Match(pattern, value)
+	return err == nil && isMatch
+}
+
+// normalize returns the json representation of the value to be compared
+func normalize(value interface{}) interface{} {
+	var valueBytes []byte
+	switch v := value.(type) {
+	case []byte:
+		valueBytes = v
+	case string:
+		valueBytes = []byte(v)
+	default:
+		var err error
+		valueBytes, err = json.Marshal(v)
+		if err != nil {
+			return value
+		}
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(valueBytes, &normalized); err != nil {
+		return string(valueBytes)
+	}
+	return normalized
+}
+
+func (e *Expectation) matches(call *Call) bool {
+	if e.method != call.Method || !matchPath(e.path, call.Path) {
+		return false
+	}
+	if e.times > 0 && e.calls >= e.times {
+		return false
+	}
+	if e.hasBody && !reflect.DeepEqual(normalize(e.body), normalize(call.Request)) {
+		return false
+	}
+	return true
+}
+
+// response returns the raw response of the expectation
+func (e *Expectation) response() (string, error) {
+	switch v := e.result.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+
+	response, err := json.Marshal(e.result)
+	return string(response), err
+}
+
+// Client is the fake client.Caller, the calls are matched against the expectations in order
+// and the acknowledged calls are registered into the context like client.HTTPClient,
+// so they can be acknowledged by the fake AcknowledgeService or client.AcknowledgeRequestService
+type Client struct {
+	mu                 sync.Mutex
+	expectations       []*Expectation
+	calls              []*Call
+	unexpected         []*Call
+	httpClient         *client.HTTPClient
+	AuthorizationTypes []client.AuthorizationType
+}
+
+// acknowledgeTransport receives the acknowledge of client.AcknowledgeRequestService and marks the acknowledged call
+type acknowledgeTransport struct {
+	client *Client
+}
+
+func (t *acknowledgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	logURL := *req.URL
+	logURL.RawQuery = ""
+
+	t.client.mu.Lock()
+	for _, call := range t.client.calls {
+		if call.Log != nil && call.Log.URL == logURL.String() {
+			call.AcknowledgeStatus = req.URL.Query().Get("s")
+		}
+	}
+	t.client.mu.Unlock()
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+// acknowledgeClient returns the http client of the acknowledge sent to the fake client
+func (c *Client) acknowledgeClient() *client.HTTPClient {
+	if c.httpClient == nil {
+		c.httpClient = client.NewHTTPClient(client.HTTPClient{
+			ClientName: "clienttest",
+			APIURL:     baseURL,
+			HTTPClient: &http.Client{Transport: &acknowledgeTransport{client: c}},
+		}, nil, nil, nil, nil)
+	}
+	return c.httpClient
+}
+
+// NewClient creates the fake client
+func NewClient() *Client {
+	return &Client{}
+}
+
+// Expect adds the expectation of the method & path, the path may be a glob pattern
+func (c *Client) Expect(method client.Method, path string) *Expectation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expectation := &Expectation{method: method, path: path}
+	c.expectations = append(c.expectations, expectation)
+	return expectation
+}
+
+// Calls returns the captured calls
+func (c *Client) Calls() []*Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Call{}, c.calls...)
+}
+
+// CallsTo returns the captured calls of the method & path
+func (c *Client) CallsTo(method client.Method, path string) []*Call {
+	calls := []*Call{}
+	for _, call := range c.Calls() {
+		if call.Method == method && matchPath(path, call.Path) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// AssertExpectations fails the test when there is an unexpected call, an expectation with Times isn't called
+// exactly n times or an expectation without Times is never called
+func (c *Client) AssertExpectations(t *testing.T) {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, call := range c.unexpected {
+		t.Errorf("unexpected call %s %s with %v", call.Method, call.Path, call.Body)
+	}
+	for _, expectation := range c.expectations {
+		if expectation.times > 0 && expectation.calls != expectation.times {
+			t.Errorf("expected %s to be called %d times, called %d times", expectation, expectation.times, expectation.calls)
+		} else if expectation.calls == 0 {
+			t.Errorf("expected %s to be called", expectation)
+		}
+	}
+}
+
+// callByLog returns the call of the client request log registered in the context
+func (c *Client) callByLog(clientRequestLog *client.ClientRequestLog) *Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, call := range c.calls {
+		if call.Log != nil && call.Log == clientRequestLog {
+			return call
+		}
+	}
+	return nil
+}
+
+// call captures the call and returns the result of the matched expectation decoded by the decoder
+func (c *Client) call(ctx *context.Context, call *Call, result interface{}, decoder client.ResponseDecoder) (string, *client.ResponseError) {
+	if call.Request != nil {
+		if body, ok := normalize(call.Request).(map[string]interface{}); ok {
+			call.Body = body
+		}
+	}
+
+	c.mu.Lock()
+	c.calls = append(c.calls, call)
+	var expectation *Expectation
+	for _, e := range c.expectations {
+		if e.matches(call) {
+			expectation = e
+			e.calls++
+			break
+		}
+	}
+	if expectation == nil {
+		c.unexpected = append(c.unexpected, call)
+	}
+	c.mu.Unlock()
+
+	if expectation == nil {
+		call.Error = &client.ResponseError{
+			Message: fmt.Sprintf("%v: %s %s", ErrUnexpectedCall, call.Method, call.Path),
+			Error:   ErrUnexpectedCall,
+		}
+		return "", call.Error
+	}
+	if expectation.err != nil {
+		call.Error = expectation.err
+		return "", call.Error
+	}
+
+	response, err := expectation.response()
+	if err == nil && response != "" && result != nil {
+		err = decoder.Decode([]byte(response), result)
+	}
+	if err != nil {
+		call.Error = &client.ResponseError{
+			Message: err.Error(),
+			Error:   err,
+		}
+		return "", call.Error
+	}
+
+	if call.IsAcknowledgeNeeded && appcontext.RequestStatus(ctx) == nil {
+		c.acknowledge(ctx, call)
+	}
+
+	// the successful call returns the response error holding the status code like client.HTTPClient
+	return response, &client.ResponseError{
+		StatusCode: http.StatusOK,
+	}
+}
+
+// acknowledge registers the client request log of the call into the context to be acknowledged on commit or rollback
+func (c *Client) acknowledge(ctx *context.Context, call *Call) {
+	logURL := call.Path
+	if !strings.HasPrefix(logURL, "http://") && !strings.HasPrefix(logURL, "https://") {
+		logURL = baseURL + "/" + strings.TrimPrefix(logURL, "/")
+	}
+
+	c.mu.Lock()
+	call.Log = &client.ClientRequestLog{
+		ID:             len(c.calls),
+		Method:         string(call.Method),
+		URL:            logURL,
+		Request:        call.Body,
+		Status:         "success",
+		HTTPStatusCode: http.StatusOK,
+		ReferenceID:    appcontext.RequestReferenceID(ctx),
+	}
+	c.mu.Unlock()
+
+	currentClientRequests := []*client.ClientRequest{}
+	temp := appcontext.ClientRequests(ctx)
+	if temp != nil {
+		currentClientRequests = temp.([]*client.ClientRequest)
+	}
+	c.mu.Lock()
+	httpClient := c.acknowledgeClient()
+	c.mu.Unlock()
+	currentClientRequests = append(currentClientRequests, &client.ClientRequest{
+		Client:  httpClient,
+		Request: call.Log,
+	})
+	*ctx = context.WithValue(*ctx, appcontext.KeyClientRequests, currentClientRequests)
+}
+
+// Do captures the request as the call of its method & url
+func (c *Client) Do(req *http.Request) (string, *client.ResponseError) {
+	call := &Call{
+		Variant: "Do",
+		Method:  client.Method(req.Method),
+		Path:    req.URL.String(),
+		Header:  req.Header,
+	}
+	if req.Body != nil {
+		body := json.RawMessage{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err == nil {
+			call.Request = []byte(body)
+		}
+	}
+
+	ctx := context.Background()
+	return c.call(&ctx, call, nil, client.JSONDecoder)
+}
+
+// Call captures the call of the method & path with the request set by the options
+func (c *Client) Call(ctx *context.Context, method client.Method, path string, opts ...client.CallOption) *client.ResponseError {
+	spec := client.NewCallSpec(opts...)
+	if spec.URL != "" {
+		path = spec.URL
+	}
+	request := spec.Request
+	if spec.RawBody != nil {
+		request = spec.RawBody
+	}
+
+	_, errDo := c.call(ctx, &Call{
+		Variant:             "Call",
+		Method:              method,
+		Path:                path,
+		Request:             request,
+		QueryParams:         spec.QueryParams,
+		Header:              spec.Header,
+		IsAcknowledgeNeeded: spec.IsAcknowledgeNeeded,
+	}, spec.Result, spec.ResponseDecoder)
+	return errDo
+}
+
+func (c *Client) callClient(ctx *context.Context, variant string, path string, method client.Method, queryParams interface{}, request interface{}, result interface{}, isAcknowledgeNeeded bool) *client.ResponseError {
+	_, errDo := c.call(ctx, &Call{
+		Variant:             variant,
+		Method:              method,
+		Path:                path,
+		Request:             request,
+		QueryParams:         queryParams,
+		IsAcknowledgeNeeded: isAcknowledgeNeeded,
+	}, result, client.JSONDecoder)
+	return errDo
+}
+
+// CallClient captures the call
+func (c *Client) CallClient(ctx *context.Context, path string, method client.Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *client.ResponseError {
+	return c.callClient(ctx, "CallClient", path, method, nil, request, result, isAcknowledgeNeeded)
+}
+
+// CallClientWithCaching captures the call
+func (c *Client) CallClientWithCaching(ctx *context.Context, path string, method client.Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *client.ResponseError {
+	return c.callClient(ctx, "CallClientWithCaching", path, method, nil, request, result, isAcknowledgeNeeded)
+}
+
+// CallClientWithCachingInRedis captures the call
+func (c *Client) CallClientWithCachingInRedis(ctx *context.Context, durationInSecond int, path string, method client.Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *client.ResponseError {
+	return c.callClient(ctx, "CallClientWithCachingInRedis", path, method, nil, request, result, isAcknowledgeNeeded)
+}
+
+// CallClientWithCachingInRedisWithDifferentKey captures the call
+func (c *Client) CallClientWithCachingInRedisWithDifferentKey(ctx *context.Context, durationInSecond int, path string, pathToBeStoredAsKey string, method client.Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *client.ResponseError {
+	return c.callClient(ctx, "CallClientWithCachingInRedisWithDifferentKey", path, method, nil, request, result, isAcknowledgeNeeded)
+}
+
+// CallClientWithCircuitBreaker captures the call
+func (c *Client) CallClientWithCircuitBreaker(ctx *context.Context, path string, method client.Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *client.ResponseError {
+	return c.callClient(ctx, "CallClientWithCircuitBreaker", path, method, nil, request, result, isAcknowledgeNeeded)
+}
+
+// CallClientWithoutLog captures the call
+func (c *Client) CallClientWithoutLog(ctx *context.Context, path string, method client.Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *client.ResponseError {
+	return c.callClient(ctx, "CallClientWithoutLog", path, method, nil, request, result, isAcknowledgeNeeded)
+}
+
+// CallClientWithBaseURLGiven captures the call as the call of the url
+func (c *Client) CallClientWithBaseURLGiven(ctx *context.Context, url string, method client.Method, request interface{}, result interface{}, isAcknowledgeNeeded bool) *client.ResponseError {
+	return c.callClient(ctx, "CallClientWithBaseURLGiven", url, method, nil, request, result, isAcknowledgeNeeded)
+}
+
+// CallClientWithCustomizedError captures the call with its query params
+func (c *Client) CallClientWithCustomizedError(ctx *context.Context, path string, method client.Method, queryParams interface{}, request interface{}, result interface{}, isAcknowledgeNeeded bool) *client.ResponseError {
+	return c.callClient(ctx, "CallClientWithCustomizedError", path, method, queryParams, request, result, isAcknowledgeNeeded)
+}
+
+// CallClientWithCustomizedErrorAndCaching captures the call with its query params
+func (c *Client) CallClientWithCustomizedErrorAndCaching(ctx *context.Context, path string, method client.Method, queryParams interface{}, request interface{}, result interface{}, isAcknowledgeNeeded bool) *client.ResponseError {
+	return c.callClient(ctx, "CallClientWithCustomizedErrorAndCaching", path, method, queryParams, request, result, isAcknowledgeNeeded)
+}
+
+// CallClientWithRequestInBytes captures the call with the raw request
+func (c *Client) CallClientWithRequestInBytes(ctx *context.Context, path string, method client.Method, request []byte, result interface{}) *client.ResponseError {
+	return c.callClient(ctx, "CallClientWithRequestInBytes", path, method, nil, request, result, false)
+}
+
+// AddAuthentication adds the authorization type
+func (c *Client) AddAuthentication(ctx *context.Context, authorizationType client.AuthorizationType) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.AuthorizationTypes = append(c.AuthorizationTypes, authorizationType)
+}
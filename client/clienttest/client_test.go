@@ -0,0 +1,201 @@
+package clienttest
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/payfazz/commerce-kit/appcontext"
+	"github.com/payfazz/commerce-kit/client"
+	"github.com/payfazz/commerce-kit/types"
+)
+
+type order struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+func TestClientMatching(t *testing.T) {
+	fake := NewClient()
+	fake.Expect(client.POST, "/orders").Once().Return(order{ID: 1, Status: "created"}, nil)
+	fake.Expect(client.POST, "/orders").Return(nil, &client.ResponseError{StatusCode: http.StatusConflict, Message: "duplicate"})
+	fake.Expect(client.GET, "/orders/1").Return(`{"id":1,"status":"paid"}`, nil)
+
+	ctx := context.Background()
+	var created order
+	errDo := fake.CallClient(&ctx, "/orders", client.POST, order{Status: "new"}, &created, false)
+	if errDo == nil || errDo.Error != nil || errDo.StatusCode != http.StatusOK || created.ID != 1 {
+		t.Fatalf("CallClient() = %+v, %+v, want the first expectation", created, errDo)
+	}
+
+	errDo = fake.CallClient(&ctx, "/orders", client.POST, order{Status: "new"}, &created, false)
+	if errDo == nil || errDo.StatusCode != http.StatusConflict {
+		t.Errorf("CallClient() = %+v, want the error of the second expectation once the first is used up", errDo)
+	}
+
+	var found order
+	errDo = fake.Call(&ctx, client.GET, "/orders/1", client.WithResult(&found))
+	if errDo.Error != nil || found.Status != "paid" {
+		t.Errorf("Call() = %+v, %+v, want the raw response parsed", found, errDo)
+	}
+
+	errDo = fake.CallClient(&ctx, "/orders/1", client.DELETE, nil, nil, false)
+	if errDo == nil || errDo.Error != ErrUnexpectedCall {
+		t.Errorf("CallClient() = %+v, want ErrUnexpectedCall", errDo)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 4 || calls[0].Variant != "CallClient" || calls[2].Variant != "Call" || calls[0].Body["status"] != "new" {
+		t.Errorf("Calls() = %+v, want the captured calls in order", calls)
+	}
+	if len(fake.CallsTo(client.POST, "/orders")) != 2 {
+		t.Errorf("CallsTo() = %+v, want the 2 calls of the path", fake.CallsTo(client.POST, "/orders"))
+	}
+}
+
+func TestClientCallOptions(t *testing.T) {
+	fake := NewClient()
+	fake.Expect(client.POST, "/payments").Return("status=paid&amount=100", nil)
+
+	ctx := context.Background()
+	var result url.Values
+	errDo := fake.Call(&ctx, client.POST, "/payments",
+		client.WithRequest(map[string]string{"amount": "100"}),
+		client.WithHeader("X-Trace", "trace"),
+		client.WithResult(&result),
+		client.WithResponseDecoder(client.FormDecoder),
+	)
+	if errDo.Error != nil || result.Get("status") != "paid" {
+		t.Fatalf("Call() = %v, %+v, want the response decoded by the decoder of the call", result, errDo)
+	}
+
+	call := fake.Calls()[0]
+	if call.Header.Get("X-Trace") != "trace" || call.Body["amount"] != "100" {
+		t.Errorf("Call() captured %+v, want the header & body of the options", call)
+	}
+	fake.AssertExpectations(t)
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isMatch bool
+	}{
+		{"/orders", "/orders", true},
+		{"orders", "/orders", true},
+		{"/orders", "orders", true},
+		{"/orders/*", "/orders/12", true},
+		{"/orders/*", "/orders/12/items", false},
+		{"/orders/*/items", "/orders/12/items", true},
+		{"/orders/?", "/orders/1", true},
+		{"/orders/?", "/orders/12", false},
+		{"/orders/[0-9]*", "/orders/12", true},
+		{"/orders/[0-9]*", "/orders/abc", false},
+		{"/orders", "/orders/12", false},
+		{"http://other/orders/*", "http://other/orders/12", true},
+		{"/orders/[", "/orders/[", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if isMatch := matchPath(tt.pattern, tt.path); isMatch != tt.isMatch {
+				t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, isMatch, tt.isMatch)
+			}
+		})
+	}
+}
+
+func TestExpectationWithBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    interface{}
+		request interface{}
+		isMatch bool
+	}{
+		{"struct & map", order{ID: 1, Status: "new"}, map[string]interface{}{"status": "new", "id": 1}, true},
+		{"map & struct", map[string]interface{}{"id": 1, "status": "new"}, order{ID: 1, Status: "new"}, true},
+		{"json string & struct", `{"status":"new","id":1}`, order{ID: 1, Status: "new"}, true},
+		{"json bytes & struct", []byte(`{"id":1,"status":"new"}`), &order{ID: 1, Status: "new"}, true},
+		{"number kinds", map[string]interface{}{"amount": 100}, map[string]interface{}{"amount": 100.0}, true},
+		{"different value", order{ID: 1, Status: "new"}, order{ID: 1, Status: "paid"}, false},
+		{"missing field", map[string]interface{}{"id": 1}, order{ID: 1, Status: "new"}, false},
+		{"raw text", "not json", []byte("not json"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectation := (&Expectation{method: client.POST, path: "/orders"}).WithBody(tt.body)
+			call := &Call{Method: client.POST, Path: "/orders", Request: tt.request}
+			if isMatch := expectation.matches(call); isMatch != tt.isMatch {
+				t.Errorf("matches() = %v, want %v", isMatch, tt.isMatch)
+			}
+		})
+	}
+}
+
+type acknowledgeRequestStorage struct {
+	client.AcknowledgeRequestStorage
+	inserted []*client.AcknowledgeRequest
+}
+
+func (s *acknowledgeRequestStorage) Insert(ctx *context.Context, acknowledgeRequest *client.AcknowledgeRequest) (*client.AcknowledgeRequest, *types.Error) {
+	s.inserted = append(s.inserted, acknowledgeRequest)
+	return acknowledgeRequest, nil
+}
+
+type clientRequestLogStorage struct {
+	client.ClientRequestLogStorage
+}
+
+func (s *clientRequestLogStorage) FindByID(ctx *context.Context, clientRequestLogID int) *client.ClientRequestLog {
+	return nil
+}
+
+func TestAcknowledgeRequestServiceRoundTrip(t *testing.T) {
+	tests := []struct {
+		status string
+	}{
+		{"commit"},
+		{"rollback"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			fake := NewClient()
+			fake.Expect(client.POST, "/reservations").Return(map[string]interface{}{"id": 7}, nil)
+			fake.Expect(client.GET, "/stock").Return(map[string]interface{}{"qty": 1}, nil)
+			storage := &acknowledgeRequestStorage{}
+			service := client.NewAcknowledgeRequestService(storage, &clientRequestLogStorage{})
+
+			ctx := context.Background()
+			fake.CallClient(&ctx, "/reservations", client.POST, map[string]interface{}{"qty": 1}, nil, true)
+			fake.CallClient(&ctx, "/stock", client.GET, nil, nil, false)
+			if err := service.Acknowledge(&ctx, tt.status, ""); err != nil {
+				t.Fatalf("Acknowledge() error = %v", err)
+			}
+
+			reservation, stock := fake.CallsTo(client.POST, "/reservations")[0], fake.CallsTo(client.GET, "/stock")[0]
+			if reservation.AcknowledgeStatus != tt.status || reservation.Log == nil || reservation.Log.URL != "http://clienttest/reservations" {
+				t.Errorf("reservation = %+v, want it acknowledged with %s", reservation, tt.status)
+			}
+			if stock.AcknowledgeStatus != "" || stock.Log != nil {
+				t.Errorf("stock = %+v, want the call without acknowledge left alone", stock)
+			}
+			if len(storage.inserted) != 1 || storage.inserted[0].CommitStatus != tt.status || storage.inserted[0].ReservedHolder["qty"] != float64(1) {
+				t.Errorf("acknowledge requests = %+v, want the acknowledged reservation", storage.inserted)
+			}
+			if status := appcontext.RequestStatus(&ctx); status == nil || *status != tt.status {
+				t.Errorf("request status = %v, want %s", status, tt.status)
+			}
+
+			// the calls after the acknowledge aren't registered again
+			fake.Expect(client.POST, "/late").Return(nil, nil)
+			fake.CallClient(&ctx, "/late", client.POST, nil, nil, true)
+			if late := fake.CallsTo(client.POST, "/late")[0]; late.Log != nil {
+				t.Errorf("late call = %+v, want it not registered after the acknowledge", late)
+			}
+		})
+	}
+}
@@ -0,0 +1,460 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/payfazz/commerce-kit/types"
+)
+
+const defaultContentType = "application/json"
+
+// BodyEncoder encodes the request of a call into the request body
+type BodyEncoder interface {
+	// Encode returns the body of the request with its content type
+	Encode(request interface{}) ([]byte, string, error)
+	// Log returns the safe representation of the body stored in the client request log
+	Log(request interface{}, body []byte) (types.Metadata, error)
+}
+
+// ResponseDecoder decodes the response body of a call into the result
+type ResponseDecoder interface {
+	Decode(body []byte, result interface{}) error
+}
+
+// Built-in body encoders
+var (
+	// JSONEncoder encodes the request as application/json, it's the default encoder
+	JSONEncoder BodyEncoder = &jsonEncoder{}
+	// FormEncoder encodes the url.Values, map or json tagged struct request as application/x-www-form-urlencoded
+	FormEncoder BodyEncoder = &formEncoder{}
+	// MultipartEncoder encodes the MultipartForm request as multipart/form-data
+	MultipartEncoder BodyEncoder = &multipartEncoder{}
+	// XMLEncoder encodes the xml tagged request as application/xml
+	XMLEncoder BodyEncoder = &xmlEncoder{}
+)
+
+// Built-in response decoders
+var (
+	// JSONDecoder decodes the application/json response, it's the default decoder
+	JSONDecoder ResponseDecoder = &jsonDecoder{}
+	// FormDecoder decodes the application/x-www-form-urlencoded response into the url.Values, map or json tagged struct result
+	FormDecoder ResponseDecoder = &formDecoder{}
+	// XMLDecoder decodes the application/xml response into the xml tagged result
+	XMLDecoder ResponseDecoder = &xmlDecoder{}
+)
+
+type jsonEncoder struct{}
+
+func (e *jsonEncoder) Encode(request interface{}) ([]byte, string, error) {
+	body, err := json.Marshal(request)
+	return body, defaultContentType, err
+}
+
+func (e *jsonEncoder) Log(request interface{}, body []byte) (types.Metadata, error) {
+	requestRaw := types.Metadata{}
+	if len(body) == 0 {
+		return requestRaw, nil
+	}
+	err := json.Unmarshal(body, &requestRaw)
+	return requestRaw, err
+}
+
+// formValues converts the url.Values, map or the json tagged fields of the struct into the form values,
+// the slices are repeated and the nested objects are sent as json
+func formValues(request interface{}) (url.Values, error) {
+	switch v := request.(type) {
+	case url.Values:
+		return v, nil
+	case *url.Values:
+		return *v, nil
+	case map[string]string:
+		values := url.Values{}
+		for key, value := range v {
+			values.Set(key, value)
+		}
+		return values, nil
+	}
+
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(requestBytes))
+	decoder.UseNumber()
+	if err = decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+	for key, field := range fields {
+		items, ok := field.([]interface{})
+		if !ok {
+			items = []interface{}{field}
+		}
+		for _, item := range items {
+			switch v := item.(type) {
+			case nil:
+			case string:
+				values.Add(key, v)
+			case json.Number, bool:
+				values.Add(key, fmt.Sprint(v))
+			default:
+				itemBytes, err := json.Marshal(v)
+				if err != nil {
+					return nil, err
+				}
+				values.Add(key, string(itemBytes))
+			}
+		}
+	}
+	return values, nil
+}
+
+// formMetadata converts the form values into the metadata, the single values are kept as string
+func formMetadata(values url.Values) types.Metadata {
+	metadata := types.Metadata{}
+	for key, value := range values {
+		if len(value) == 1 {
+			metadata[key] = value[0]
+		} else {
+			metadata[key] = value
+		}
+	}
+	return metadata
+}
+
+type formEncoder struct{}
+
+func (e *formEncoder) Encode(request interface{}) ([]byte, string, error) {
+	values, err := formValues(request)
+	if err != nil {
+		return nil, "", err
+	}
+	return []byte(values.Encode()), "application/x-www-form-urlencoded", nil
+}
+
+func (e *formEncoder) Log(request interface{}, body []byte) (types.Metadata, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	return formMetadata(values), nil
+}
+
+// MultipartFile represents a file part of the multipart body
+type MultipartFile struct {
+	FieldName   string
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+// MultipartForm represents the request of the multipart body,
+// Fields is the url.Values, map or json tagged struct encoded like the form body
+type MultipartForm struct {
+	Fields interface{}
+	Files  []MultipartFile
+}
+
+type multipartEncoder struct{}
+
+func (e *multipartEncoder) Encode(request interface{}) ([]byte, string, error) {
+	form, ok := request.(*MultipartForm)
+	if !ok {
+		value, isValue := request.(MultipartForm)
+		if !isValue {
+			return nil, "", fmt.Errorf("multipart request must be a MultipartForm, got %T", request)
+		}
+		form = &value
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if form.Fields != nil {
+		values, err := formValues(form.Fields)
+		if err != nil {
+			return nil, "", err
+		}
+		for key, value := range values {
+			for _, v := range value {
+				if err = writer.WriteField(key, v); err != nil {
+					return nil, "", err
+				}
+			}
+		}
+	}
+
+	for _, file := range form.Files {
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		partHeader := textproto.MIMEHeader{}
+		partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(file.FieldName), escapeQuotes(file.FileName)))
+		partHeader.Set("Content-Type", contentType)
+
+		part, err := writer.CreatePart(partHeader)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err = part.Write(file.Content); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
+
+// Log returns the fields and only the name, content type, size & checksum of the files
+func (e *multipartEncoder) Log(request interface{}, body []byte) (types.Metadata, error) {
+	form, ok := request.(*MultipartForm)
+	if !ok {
+		value, isValue := request.(MultipartForm)
+		if !isValue {
+			return types.Metadata{"size": len(body)}, nil
+		}
+		form = &value
+	}
+
+	fields := types.Metadata{}
+	if form.Fields != nil {
+		values, err := formValues(form.Fields)
+		if err != nil {
+			return nil, err
+		}
+		fields = formMetadata(values)
+	}
+
+	files := []types.Metadata{}
+	for _, file := range form.Files {
+		checksum := sha256.Sum256(file.Content)
+		files = append(files, types.Metadata{
+			"fieldName":   file.FieldName,
+			"fileName":    file.FileName,
+			"contentType": file.ContentType,
+			"size":        len(file.Content),
+			"sha256":      hex.EncodeToString(checksum[:]),
+		})
+	}
+
+	return types.Metadata{
+		"fields": fields,
+		"files":  files,
+	}, nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+type xmlEncoder struct{}
+
+func (e *xmlEncoder) Encode(request interface{}) ([]byte, string, error) {
+	body, err := xml.Marshal(request)
+	if err != nil {
+		return nil, "", err
+	}
+	return append([]byte(xml.Header), body...), "application/xml; charset=utf-8", nil
+}
+
+// Log returns the json representation of the request so its body paths can be redacted,
+// only the size & checksum of the body otherwise
+func (e *xmlEncoder) Log(request interface{}, body []byte) (types.Metadata, error) {
+	if request != nil {
+		requestRaw := types.Metadata{}
+		requestBytes, err := json.Marshal(request)
+		if err == nil && json.Unmarshal(requestBytes, &requestRaw) == nil {
+			return requestRaw, nil
+		}
+	}
+	checksum := sha256.Sum256(body)
+	return types.Metadata{"size": len(body), "sha256": hex.EncodeToString(checksum[:])}, nil
+}
+
+type jsonDecoder struct{}
+
+func (d *jsonDecoder) Decode(body []byte, result interface{}) error {
+	return json.Unmarshal(body, result)
+}
+
+type formDecoder struct{}
+
+func (d *formDecoder) Decode(body []byte, result interface{}) error {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+
+	switch v := result.(type) {
+	case *url.Values:
+		*v = values
+		return nil
+	case *map[string]string:
+		*v = map[string]string{}
+		for key := range values {
+			(*v)[key] = values.Get(key)
+		}
+		return nil
+	case *map[string]interface{}:
+		*v = formMetadata(values)
+		return nil
+	case *types.Metadata:
+		*v = formMetadata(values)
+		return nil
+	}
+
+	resultValue := reflect.ValueOf(result)
+	if resultValue.Kind() != reflect.Ptr || resultValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("form result must be a url.Values, map or struct pointer, got %T", result)
+	}
+	return decodeFormStruct(values, resultValue.Elem())
+}
+
+// decodeFormStruct sets the fields of the struct by its json tags (or its names) from the form values,
+// the values are converted to the kind of the field and the slices take the repeated values
+func decodeFormStruct(values url.Values, v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+
+		fieldValues, ok := values[key]
+		if !ok {
+			for name, value := range values {
+				if strings.EqualFold(name, key) {
+					fieldValues, ok = value, true
+				}
+			}
+		}
+		if !ok || len(fieldValues) == 0 {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		if fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(fieldValue.Type(), len(fieldValues), len(fieldValues))
+			for j, value := range fieldValues {
+				if err := setFormValue(slice.Index(j), value); err != nil {
+					return fmt.Errorf("error when decoding the form field %s: %v", key, err)
+				}
+			}
+			fieldValue.Set(slice)
+			continue
+		}
+
+		if err := setFormValue(fieldValue, fieldValues[0]); err != nil {
+			return fmt.Errorf("error when decoding the form field %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// setFormValue sets the form value into the field converted by its kind, the other kinds are decoded as json
+func setFormValue(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := setFormValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		// the nested objects are sent as json by the form encoder
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+	return nil
+}
+
+type xmlDecoder struct{}
+
+func (d *xmlDecoder) Decode(body []byte, result interface{}) error {
+	return xml.Unmarshal(body, result)
+}
+
+// bodyPlaceholder returns the size & checksum logged in place of the body that can't be redacted by its paths
+func bodyPlaceholder(kind string, body []byte) string {
+	checksum := sha256.Sum256(body)
+	return fmt.Sprintf("[%s body of %d bytes, sha256:%s]", kind, len(body), hex.EncodeToString(checksum[:]))
+}
+
+// logBody returns the body of the curl command of the client request log
+func (r *redactor) logBody(encoder BodyEncoder, body []byte) string {
+	switch encoder.(type) {
+	case *jsonEncoder:
+		return r.JSON(string(body))
+	case *formEncoder:
+		return r.Form(string(body))
+	case *multipartEncoder:
+		return bodyPlaceholder("multipart", body)
+	case *xmlEncoder:
+		return bodyPlaceholder("xml", body)
+	}
+	return r.String(string(body))
+}
+
+// logResponse returns the response of the client request log redacted by the format of its decoder
+func (r *redactor) logResponse(decoder ResponseDecoder, response string) string {
+	switch decoder.(type) {
+	case *formDecoder:
+		return r.Form(response)
+	case *xmlDecoder:
+		return bodyPlaceholder("xml", []byte(response))
+	}
+	return r.JSON(response)
+}
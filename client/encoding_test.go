@@ -0,0 +1,100 @@
+package client
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type formResult struct {
+	Amount   int      `json:"amount"`
+	OK       bool     `json:"ok"`
+	Rate     float64  `json:"rate"`
+	Count    uint     `json:"count"`
+	Name     string   `json:"name"`
+	Tags     []string `json:"tags"`
+	Note     *string  `json:"note"`
+	Ignored  string   `json:"-"`
+	Untagged int
+}
+
+func TestFormDecoderStruct(t *testing.T) {
+	note := "paid"
+	tests := []struct {
+		name    string
+		body    string
+		want    formResult
+		wantErr bool
+	}{
+		{"int & bool", "amount=100&ok=true", formResult{Amount: 100, OK: true}, false},
+		{"float & uint", "rate=1.5&count=3", formResult{Rate: 1.5, Count: 3}, false},
+		{"repeated values", "tags=a&tags=b", formResult{Tags: []string{"a", "b"}}, false},
+		{"pointer", "note=paid", formResult{Note: &note}, false},
+		{"field name", "Untagged=7&Ignored=x", formResult{Untagged: 7}, false},
+		{"escaped", "name=a+b%26c", formResult{Name: "a b&c"}, false},
+		{"invalid int", "amount=x", formResult{}, true},
+		{"invalid bool", "ok=maybe", formResult{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result formResult
+			err := FormDecoder.Decode([]byte(tt.body), &result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormDecoderMaps(t *testing.T) {
+	body := []byte("amount=100&tags=a&tags=b")
+
+	var values url.Values
+	if err := FormDecoder.Decode(body, &values); err != nil || values.Get("amount") != "100" {
+		t.Errorf("Decode(url.Values) = %v, %v", values, err)
+	}
+
+	var fields map[string]interface{}
+	if err := FormDecoder.Decode(body, &fields); err != nil || fields["amount"] != "100" || len(fields["tags"].([]string)) != 2 {
+		t.Errorf("Decode(map) = %v, %v", fields, err)
+	}
+
+	var notStruct int
+	if err := FormDecoder.Decode(body, &notStruct); err == nil {
+		t.Error("Decode(*int) error = nil, want an error")
+	}
+}
+
+func TestLogBodyPlaceholder(t *testing.T) {
+	r := newRedactor(&RedactionPolicy{BodyPaths: []string{"password"}}, nil)
+	body := []byte("<login><password>secret</password></login>")
+
+	tests := []struct {
+		name   string
+		logged string
+	}{
+		{"xml request", r.logBody(XMLEncoder, body)},
+		{"multipart request", r.logBody(MultipartEncoder, body)},
+		{"xml response", r.logResponse(XMLDecoder, string(body))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if strings.Contains(tt.logged, "secret") {
+				t.Errorf("logged %q, want the body hidden", tt.logged)
+			}
+			if !strings.Contains(tt.logged, "42 bytes, sha256:") {
+				t.Errorf("logged %q, want the size & checksum", tt.logged)
+			}
+		})
+	}
+
+	if logged := r.logResponse(FormDecoder, "user=a&password=secret"); strings.Contains(logged, "secret") {
+		t.Errorf("logResponse(form) = %q, want the password redacted", logged)
+	}
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/payfazz/commerce-kit/types"
+)
+
+// ErrorDecoder decodes the body of a non 2xx response into the normalised ResponseError.
+// The zero StatusCode & Code are filled from the response, Raw & Retryable are filled by the client too.
+type ErrorDecoder interface {
+	Decode(res *http.Response, body []byte) *ResponseError
+}
+
+// ErrorDecoderFunc is the function implementation of ErrorDecoder
+type ErrorDecoderFunc func(res *http.Response, body []byte) *ResponseError
+
+// Decode decodes the error response by the function
+func (f ErrorDecoderFunc) Decode(res *http.Response, body []byte) *ResponseError {
+	return f(res, body)
+}
+
+// Built-in error decoders
+var (
+	// GenericErrorDecoder decodes {"code", "message", "fields" or "errors"}
+	GenericErrorDecoder ErrorDecoder = ErrorDecoderFunc(decodeGenericError)
+	// AnterajaErrorDecoder decodes {"status", "info"}
+	AnterajaErrorDecoder ErrorDecoder = ErrorDecoderFunc(decodeAnterajaError)
+	// ProblemErrorDecoder decodes the RFC 7807 problem details
+	ProblemErrorDecoder ErrorDecoder = ErrorDecoderFunc(decodeProblemError)
+	// RawErrorDecoder uses the raw body as the error message
+	RawErrorDecoder ErrorDecoder = ErrorDecoderFunc(decodeRawError)
+	// DefaultErrorDecoder decodes the problem details by its content type, otherwise the generic & Anteraja shapes
+	DefaultErrorDecoder ErrorDecoder = ErrorDecoderFunc(decodeDefaultError)
+)
+
+// fieldErrors converts the field errors of the body into the metadata
+func fieldErrors(fields json.RawMessage) types.Metadata {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	metadata := types.Metadata{}
+	if err := json.Unmarshal(fields, &metadata); err == nil {
+		return metadata
+	}
+
+	// the list of {"field"/"name", "message"/"reason"}
+	list := []struct {
+		Field   string `json:"field"`
+		Name    string `json:"name"`
+		Message string `json:"message"`
+		Reason  string `json:"reason"`
+	}{}
+	if err := json.Unmarshal(fields, &list); err != nil {
+		return nil
+	}
+	for _, item := range list {
+		field := item.Field
+		if field == "" {
+			field = item.Name
+		}
+		message := item.Message
+		if message == "" {
+			message = item.Reason
+		}
+		if field != "" {
+			metadata[field] = message
+		}
+	}
+	return metadata
+}
+
+func decodeGenericError(res *http.Response, body []byte) *ResponseError {
+	errResponse := &ResponseError{}
+	generic := struct {
+		Code       interface{}     `json:"code"`
+		Message    string          `json:"message"`
+		StatusCode int             `json:"statusCode"`
+		Fields     json.RawMessage `json:"fields"`
+		Errors     json.RawMessage `json:"errors"`
+	}{}
+	if err := json.Unmarshal(body, &generic); err != nil {
+		return errResponse
+	}
+
+	if generic.Code != nil {
+		errResponse.Code = fmt.Sprintf("%v", generic.Code)
+	}
+	errResponse.Message = generic.Message
+	errResponse.StatusCode = generic.StatusCode
+	errResponse.Fields = fieldErrors(generic.Fields)
+	if errResponse.Fields == nil {
+		errResponse.Fields = fieldErrors(generic.Errors)
+	}
+	return errResponse
+}
+
+func decodeAnterajaError(res *http.Response, body []byte) *ResponseError {
+	errResponse := &ResponseError{}
+	if err := json.Unmarshal(body, &struct {
+		Status *int    `json:"status"`
+		Info   *string `json:"info"`
+	}{&errResponse.Status, &errResponse.Info}); err != nil {
+		return errResponse
+	}
+
+	errResponse.Message = errResponse.Info
+	errResponse.StatusCode = errResponse.Status
+	return errResponse
+}
+
+func decodeProblemError(res *http.Response, body []byte) *ResponseError {
+	errResponse := &ResponseError{}
+	problem := struct {
+		Type          string          `json:"type"`
+		Title         string          `json:"title"`
+		Status        int             `json:"status"`
+		Detail        string          `json:"detail"`
+		InvalidParams json.RawMessage `json:"invalid-params"`
+		Errors        json.RawMessage `json:"errors"`
+	}{}
+	if err := json.Unmarshal(body, &problem); err != nil {
+		return errResponse
+	}
+
+	errResponse.Code = problem.Type
+	if errResponse.Code == "" || errResponse.Code == "about:blank" {
+		errResponse.Code = problem.Title
+	}
+	errResponse.Message = problem.Detail
+	if errResponse.Message == "" {
+		errResponse.Message = problem.Title
+	}
+	errResponse.StatusCode = problem.Status
+	errResponse.Fields = fieldErrors(problem.InvalidParams)
+	if errResponse.Fields == nil {
+		errResponse.Fields = fieldErrors(problem.Errors)
+	}
+	return errResponse
+}
+
+func decodeRawError(res *http.Response, body []byte) *ResponseError {
+	return &ResponseError{
+		Message: string(body),
+		Error:   errors.New(string(body)),
+	}
+}
+
+func decodeDefaultError(res *http.Response, body []byte) *ResponseError {
+	if strings.Contains(res.Header.Get("Content-Type"), "application/problem+json") {
+		return decodeProblemError(res, body)
+	}
+
+	errResponse := decodeGenericError(res, body)
+	anteraja := decodeAnterajaError(res, body)
+	errResponse.Status, errResponse.Info = anteraja.Status, anteraja.Info
+	if anteraja.Info != "" {
+		errResponse.Message = anteraja.Info
+	}
+	if anteraja.Status != 0 {
+		errResponse.StatusCode = anteraja.Status
+	}
+	return errResponse
+}
+
+// errorDecoder returns the error decoder of the client
+func (c *HTTPClient) errorDecoder() ErrorDecoder {
+	if c.ErrorDecoder != nil {
+		return c.ErrorDecoder
+	}
+	return DefaultErrorDecoder
+}
+
+// decodeError decodes the error response by the decoder and normalises it
+func (c *HTTPClient) decodeError(decoder ErrorDecoder, policy *RetryPolicy, req *http.Request, res *http.Response, body []byte) *ResponseError {
+	errResponse := decoder.Decode(res, body)
+	if errResponse == nil {
+		errResponse = &ResponseError{}
+	}
+
+	if errResponse.Code == "" {
+		errResponse.Code = fmt.Sprintf("%d", res.StatusCode)
+	}
+	if errResponse.StatusCode == 0 {
+		errResponse.StatusCode = res.StatusCode
+	}
+	errResponse.Raw = body
+	errResponse.Retryable = errResponse.Retryable || policy.isRetryStatusCode(res.StatusCode)
+	if errResponse.Error == nil {
+		errResponse.Error = fmt.Errorf("Error while calling %s: %v", c.redactor().URL(req.URL.String()), errResponse.Message)
+	}
+	return errResponse
+}
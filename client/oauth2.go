@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const defaultTokenExpiryDelta = 30 * time.Second
+
+// AuthProvider provides the authorization of the requests of a client
+type AuthProvider interface {
+	// Authorize sets the authorization header of the request and returns the token used
+	Authorize(ctx *context.Context, header http.Header) (string, error)
+	// Invalidate drops the cached token when it's still the token given, so the next Authorize fetches a new one
+	Invalidate(ctx *context.Context, token string) error
+}
+
+// OAuth2Config represents the config of the OAuth2 client credentials grant.
+// The client credentials are sent by basic auth unless AuthInParams is true.
+// The token is refreshed ExpiryDelta before its expiry, it's cached in redis when the redis client is given.
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	AuthInParams bool
+	ExpiryDelta  time.Duration
+	HTTPClient   *http.Client
+}
+
+// oauth2Token represents the cached access token
+type oauth2Token struct {
+	AccessToken string    `json:"accessToken"`
+	TokenType   string    `json:"tokenType"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+func (t *oauth2Token) isValid() bool {
+	return t != nil && t.AccessToken != "" && time.Now().Before(t.Expiry)
+}
+
+// OAuth2ClientCredentials is the AuthProvider fetching the access token by the client credentials grant,
+// it's safe for concurrent use and the token is fetched once for the concurrent requests
+type OAuth2ClientCredentials struct {
+	config      OAuth2Config
+	redisClient *redis.Client
+	mu          sync.Mutex
+	token       *oauth2Token
+}
+
+func (p *OAuth2ClientCredentials) cacheKey() string {
+	return fmt.Sprintf("oauth2token:%s:%s", p.config.TokenURL, p.config.ClientID)
+}
+
+// cachedToken returns the token cached in redis
+func (p *OAuth2ClientCredentials) cachedToken() *oauth2Token {
+	if p.redisClient == nil {
+		return nil
+	}
+
+	val, err := p.redisClient.Get(p.cacheKey()).Result()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("[OAuth2ClientCredentials] Error when getting the cached token of %s: %v", p.config.ClientID, err)
+		}
+		return nil
+	}
+
+	token := &oauth2Token{}
+	if err = json.Unmarshal([]byte(val), token); err != nil {
+		return nil
+	}
+	return token
+}
+
+func (p *OAuth2ClientCredentials) cacheToken(token *oauth2Token) {
+	if p.redisClient == nil {
+		return
+	}
+
+	value, err := json.Marshal(token)
+	if err == nil {
+		err = p.redisClient.Set(p.cacheKey(), value, time.Until(token.Expiry)).Err()
+	}
+	if err != nil {
+		log.Printf("[OAuth2ClientCredentials] Error when caching the token of %s: %v", p.config.ClientID, err)
+	}
+}
+
+// fetchToken requests a new access token from the token endpoint
+func (p *OAuth2ClientCredentials) fetchToken(ctx *context.Context) (*oauth2Token, error) {
+	params := url.Values{}
+	params.Set("grant_type", "client_credentials")
+	if len(p.config.Scopes) > 0 {
+		params.Set("scope", strings.Join(p.config.Scopes, " "))
+	}
+	if p.config.AuthInParams {
+		params.Set("client_id", p.config.ClientID)
+		params.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.config.TokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(*ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !p.config.AuthInParams {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	client := p.config.HTTPClient
+	if client == nil {
+		client = httpClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("Error while fetching token from %s: %d %s", p.config.TokenURL, res.StatusCode, string(body))
+	}
+
+	tokenResponse := struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	if err = json.Unmarshal(body, &tokenResponse); err != nil {
+		return nil, err
+	}
+	if tokenResponse.AccessToken == "" {
+		return nil, fmt.Errorf("Error while fetching token from %s: empty access token", p.config.TokenURL)
+	}
+
+	tokenType := tokenResponse.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+
+	expiresIn := time.Duration(tokenResponse.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = time.Hour
+	}
+	expiryDelta := p.config.ExpiryDelta
+	if expiryDelta <= 0 {
+		expiryDelta = defaultTokenExpiryDelta
+	}
+	if expiryDelta >= expiresIn {
+		expiryDelta = expiresIn / 2
+	}
+
+	return &oauth2Token{
+		AccessToken: tokenResponse.AccessToken,
+		TokenType:   tokenType,
+		Expiry:      time.Now().Add(expiresIn - expiryDelta),
+	}, nil
+}
+
+// Authorize sets the bearer token of the request, the token is fetched when it's not cached or expired
+func (p *OAuth2ClientCredentials) Authorize(ctx *context.Context, header http.Header) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.token.isValid() {
+		token := p.cachedToken()
+		if !token.isValid() {
+			var err error
+			token, err = p.fetchToken(ctx)
+			if err != nil {
+				return "", err
+			}
+			p.cacheToken(token)
+		}
+		p.token = token
+	}
+
+	header.Set("Authorization", fmt.Sprintf("%s %s", p.token.TokenType, p.token.AccessToken))
+	return p.token.AccessToken, nil
+}
+
+// Invalidate drops the cached token when it's still the token given
+func (p *OAuth2ClientCredentials) Invalidate(ctx *context.Context, token string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token == nil || p.token.AccessToken != token {
+		return nil
+	}
+	p.token = nil
+
+	if p.redisClient != nil {
+		if cached := p.cachedToken(); cached != nil && cached.AccessToken == token {
+			return p.redisClient.Del(p.cacheKey()).Err()
+		}
+	}
+	return nil
+}
+
+// NewOAuth2ClientCredentials creates the OAuth2 client credentials auth provider, the redis client is optional
+func NewOAuth2ClientCredentials(config OAuth2Config, redisClient *redis.Client) *OAuth2ClientCredentials {
+	return &OAuth2ClientCredentials{
+		config:      config,
+		redisClient: redisClient,
+	}
+}
+
+// authMiddleware authorizes the request by the auth provider of the client,
+// the request is retried once with a new token when it's unauthorized
+func (c *HTTPClient) authMiddleware(next CallHandler) CallHandler {
+	return func(ctx *context.Context, call *CallRequest) (string, *ResponseError) {
+		token, err := c.AuthProvider.Authorize(ctx, call.Header)
+		if err != nil {
+			return "", &ResponseError{
+				Message: err.Error(),
+				Error:   err,
+			}
+		}
+
+		response, errDo := next(ctx, call)
+		if errDo == nil || errDo.StatusCode != http.StatusUnauthorized {
+			return response, errDo
+		}
+
+		err = c.AuthProvider.Invalidate(ctx, token)
+		if err != nil {
+			log.Printf("[HTTPClient] Error when invalidating the token of %s: %v", c.ClientName, err)
+		}
+		_, err = c.AuthProvider.Authorize(ctx, call.Header)
+		if err != nil {
+			return response, errDo
+		}
+		return next(ctx, call)
+	}
+}
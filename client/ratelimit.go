@@ -0,0 +1,222 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// ErrRateLimited is the error of the call rejected by the client rate limiter
+var ErrRateLimited = errors.New("client rate limit exceeded")
+
+// RateLimit represents a token bucket refilled by Rate tokens every Per and holding up to Burst tokens
+type RateLimit struct {
+	Rate  int
+	Per   time.Duration
+	Burst int
+}
+
+// RateLimitConfig represents the rate limit config of a client.
+// Paths are the limits of the paths matching its pattern (path.Match), each of them has its own bucket.
+// The call waits up to MaxWait for its token, FailFast rejects the call right away instead.
+// The bucket is shared through redis when the client has a redis client, it's in-process otherwise.
+type RateLimitConfig struct {
+	RateLimit
+	Paths    map[string]RateLimit
+	FailFast bool
+	MaxWait  time.Duration
+}
+
+// tokensPerMs returns the refill rate of the bucket
+func (l RateLimit) tokensPerMs() float64 {
+	per := l.Per
+	if per <= 0 {
+		per = time.Second
+	}
+	return float64(l.Rate) / float64(per/time.Millisecond)
+}
+
+func (l RateLimit) burst() float64 {
+	if l.Burst <= 0 {
+		return 1
+	}
+	return float64(l.Burst)
+}
+
+// rateLimiter takes a token of the bucket, it returns the wait until the token is available
+// or false when the wait would exceed the max wait
+type rateLimiter interface {
+	Take(key string, limit RateLimit, maxWait time.Duration) (time.Duration, bool, error)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// localRateLimiter is the in-process token bucket limiter
+type localRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func (l *localRateLimiter) Take(key string, limit RateLimit, maxWait time.Duration) (time.Duration, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: limit.burst(), last: now}
+		l.buckets[key] = bucket
+	}
+
+	rate := limit.tokensPerMs()
+	elapsedMs := float64(now.Sub(bucket.last)) / float64(time.Millisecond)
+	tokens := math.Min(limit.burst(), bucket.tokens+elapsedMs*rate) - 1
+
+	wait := time.Duration(0)
+	if tokens < 0 {
+		wait = time.Duration(math.Ceil(-tokens/rate)) * time.Millisecond
+	}
+	if wait > maxWait {
+		return wait, false, nil
+	}
+
+	bucket.tokens, bucket.last = tokens, now
+	return wait, true, nil
+}
+
+var defaultLocalRateLimiter = &localRateLimiter{buckets: map[string]*tokenBucket{}}
+
+// rateLimitScript takes a token of the bucket hash atomically, it returns the wait in ms or -1 when it's rejected
+var rateLimitScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local maxWait = tonumber(ARGV[4])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(bucket[1]) or burst
+local last = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate) - 1
+local wait = 0
+if tokens < 0 then
+	wait = math.ceil(-tokens / rate)
+end
+if wait > maxWait then
+	return -1
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "last", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate) + wait + 1000)
+return wait
+`)
+
+// redisRateLimiter is the token bucket limiter shared through redis
+type redisRateLimiter struct {
+	client *redis.Client
+}
+
+func (l *redisRateLimiter) Take(key string, limit RateLimit, maxWait time.Duration) (time.Duration, bool, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	wait, err := rateLimitScript.Run(l.client, []string{key},
+		strconv.FormatFloat(limit.tokensPerMs(), 'g', -1, 64),
+		limit.burst(),
+		now,
+		int64(maxWait/time.Millisecond),
+	).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	if wait < 0 {
+		return maxWait, false, nil
+	}
+	return time.Duration(wait) * time.Millisecond, true, nil
+}
+
+// rateLimit returns the key & the limit of the bucket of the path
+func (c *HTTPClient) rateLimit(callPath string) (string, RateLimit) {
+	key := "ratelimit:" + c.ClientName
+	limit := c.RateLimitConfig.RateLimit
+
+	pattern := ""
+	for p := range c.RateLimitConfig.Paths {
+		if ok, _ := path.Match(p, callPath); ok && len(p) > len(pattern) {
+			pattern = p
+		}
+	}
+	if pattern != "" {
+		key = key + ":" + pattern
+		limit = c.RateLimitConfig.Paths[pattern]
+	}
+	return key, limit
+}
+
+// takeRateLimitToken takes a token of the bucket and waits for it, the wait is added to the call
+func (c *HTTPClient) takeRateLimitToken(ctx *context.Context, call *CallRequest, key string, limit RateLimit) *ResponseError {
+	maxWait := c.RateLimitConfig.MaxWait
+	if c.RateLimitConfig.FailFast {
+		maxWait = 0
+	}
+
+	var limiter rateLimiter = defaultLocalRateLimiter
+	if c.redisClient != nil {
+		limiter = &redisRateLimiter{client: c.redisClient}
+	}
+
+	wait, ok, err := limiter.Take(key, limit, maxWait)
+	if err != nil {
+		log.Printf("[HTTPClient] Error when taking the rate limit token of %s from redis, fallback to in-process: %v", key, err)
+		wait, ok, _ = defaultLocalRateLimiter.Take(key, limit, maxWait)
+	}
+	if !ok {
+		return &ResponseError{
+			Message:    ErrRateLimited.Error(),
+			StatusCode: http.StatusTooManyRequests,
+			Error:      ErrRateLimited,
+		}
+	}
+
+	if wait > 0 {
+		call.RateLimitWait += wait
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-(*ctx).Done():
+			timer.Stop()
+			return &ResponseError{
+				Error: (*ctx).Err(),
+			}
+		}
+	}
+	return nil
+}
+
+// rateLimitMiddleware takes a token of the bucket of the client or its path before every attempt of the call,
+// so the retries are limited too. The wait is recorded in the client request log
+func (c *HTTPClient) rateLimitMiddleware(next CallHandler) CallHandler {
+	return func(ctx *context.Context, call *CallRequest) (string, *ResponseError) {
+		key, limit := c.rateLimit(call.Path)
+		if limit.Rate <= 0 {
+			return next(ctx, call)
+		}
+
+		beforeAttempt := call.beforeAttempt
+		call.beforeAttempt = func() *ResponseError {
+			if beforeAttempt != nil {
+				if errDo := beforeAttempt(); errDo != nil {
+					return errDo
+				}
+			}
+			return c.takeRateLimitToken(ctx, call, key, limit)
+		}
+		return next(ctx, call)
+	}
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLocalRateLimiterTake(t *testing.T) {
+	limiter := &localRateLimiter{buckets: map[string]*tokenBucket{}}
+	limit := RateLimit{Rate: 10, Per: time.Second, Burst: 2}
+
+	tests := []struct {
+		name     string
+		maxWait  time.Duration
+		ok       bool
+		minWait  time.Duration
+		maxDelay time.Duration
+	}{
+		{"first burst token", 0, true, 0, 0},
+		{"second burst token", 0, true, 0, 0},
+		{"fail fast when empty", 0, false, 90 * time.Millisecond, 100 * time.Millisecond},
+		{"rejection doesn't consume", 0, false, 90 * time.Millisecond, 100 * time.Millisecond},
+		{"wait for the refill", time.Second, true, 90 * time.Millisecond, 100 * time.Millisecond},
+		{"wait for the next refill", time.Second, true, 190 * time.Millisecond, 200 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		wait, ok, err := limiter.Take("key", limit, tt.maxWait)
+		if err != nil || ok != tt.ok || wait < tt.minWait || wait > tt.maxDelay {
+			t.Fatalf("%s: Take() = %v, %v, %v, want %v within [%v, %v]", tt.name, wait, ok, err, tt.ok, tt.minWait, tt.maxDelay)
+		}
+	}
+
+	// the bucket is refilled up to its burst
+	limiter.buckets["key"].last = time.Now().Add(-time.Minute)
+	for i := 0; i < 2; i++ {
+		if wait, ok, _ := limiter.Take("key", limit, 0); !ok || wait != 0 {
+			t.Fatalf("Take() after the refill = %v, %v, want the burst token", wait, ok)
+		}
+	}
+	if _, ok, _ := limiter.Take("key", limit, 0); ok {
+		t.Error("Take() beyond the burst = true, want false")
+	}
+
+	// the buckets are separated by its key
+	if wait, ok, _ := limiter.Take("other", limit, 0); !ok || wait != 0 {
+		t.Errorf("Take() of another key = %v, %v, want its own bucket", wait, ok)
+	}
+}
+
+func TestRateLimitDefaults(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       RateLimit
+		tokensPerMs float64
+		burst       float64
+	}{
+		{"per second by default", RateLimit{Rate: 100}, 0.1, 1},
+		{"per minute", RateLimit{Rate: 60, Per: time.Minute, Burst: 5}, 0.001, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.limit.tokensPerMs() != tt.tokensPerMs || tt.limit.burst() != tt.burst {
+				t.Errorf("tokensPerMs() = %v, burst() = %v, want %v, %v", tt.limit.tokensPerMs(), tt.limit.burst(), tt.tokensPerMs, tt.burst)
+			}
+		})
+	}
+}
+
+func TestRateLimitPath(t *testing.T) {
+	c := &HTTPClient{
+		ClientName: "payment",
+		RateLimitConfig: &RateLimitConfig{
+			RateLimit: RateLimit{Rate: 100},
+			Paths: map[string]RateLimit{
+				"/orders/*":        {Rate: 10},
+				"/orders/*/refund": {Rate: 1},
+			},
+		},
+	}
+
+	tests := []struct {
+		path string
+		key  string
+		rate int
+	}{
+		{"/balance", "ratelimit:payment", 100},
+		{"/orders/1", "ratelimit:payment:/orders/*", 10},
+		{"/orders/1/refund", "ratelimit:payment:/orders/*/refund", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			key, limit := c.rateLimit(tt.path)
+			if key != tt.key || limit.Rate != tt.rate {
+				t.Errorf("rateLimit(%q) = %q, %v, want %q, %v", tt.path, key, limit.Rate, tt.key, tt.rate)
+			}
+		})
+	}
+}
+
+func TestTakeRateLimitToken(t *testing.T) {
+	limit := RateLimit{Rate: 10, Burst: 1}
+	ctx := context.Background()
+
+	failFast := &HTTPClient{RateLimitConfig: &RateLimitConfig{FailFast: true, MaxWait: time.Second}}
+	call := &CallRequest{}
+	if errDo := failFast.takeRateLimitToken(&ctx, call, "ratelimit:test-fail-fast", limit); errDo != nil {
+		t.Fatalf("takeRateLimitToken() = %+v, want the burst token", errDo)
+	}
+	errDo := failFast.takeRateLimitToken(&ctx, call, "ratelimit:test-fail-fast", limit)
+	if errDo == nil || errDo.Error != ErrRateLimited || errDo.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("takeRateLimitToken() = %+v, want ErrRateLimited", errDo)
+	}
+
+	waiting := &HTTPClient{RateLimitConfig: &RateLimitConfig{MaxWait: time.Second}}
+	call = &CallRequest{}
+	waiting.takeRateLimitToken(&ctx, call, "ratelimit:test-wait", limit)
+	startedAt := time.Now()
+	if errDo := waiting.takeRateLimitToken(&ctx, call, "ratelimit:test-wait", limit); errDo != nil {
+		t.Fatalf("takeRateLimitToken() = %+v, want the token after the wait", errDo)
+	}
+	if elapsed := time.Since(startedAt); elapsed < 90*time.Millisecond || call.RateLimitWait < 90*time.Millisecond {
+		t.Errorf("takeRateLimitToken() waited %v recorded %v, want about 100ms", elapsed, call.RateLimitWait)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	errDo = waiting.takeRateLimitToken(&cancelled, &CallRequest{}, "ratelimit:test-wait", limit)
+	if errDo == nil || errDo.Error != context.Canceled {
+		t.Errorf("takeRateLimitToken() = %+v, want the cancelled wait", errDo)
+	}
+}
@@ -0,0 +1,299 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// RecorderMode represents the enum for the mode of Recorder
+type RecorderMode string
+
+// Enum value for the mode of Recorder
+const (
+	// ModeReplay serves the requests from the cassette and fails the unmatched requests
+	ModeReplay RecorderMode = "replay"
+	// ModeRecord sends the requests and records them into the cassette
+	ModeRecord RecorderMode = "record"
+)
+
+// ErrUnmatchedInteraction is returned in replay mode when the request isn't recorded in the cassette
+var ErrUnmatchedInteraction = errors.New("no interaction of the cassette matches the request")
+
+// CassetteRequest represents a recorded request
+type CassetteRequest struct {
+	Method string      `json:"method" yaml:"method"`
+	URL    string      `json:"url" yaml:"url"`
+	Header http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body   string      `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// CassetteResponse represents a recorded response
+type CassetteResponse struct {
+	StatusCode int         `json:"statusCode" yaml:"statusCode"`
+	Header     http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body       string      `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Interaction represents a recorded request with its response
+type Interaction struct {
+	Request  CassetteRequest  `json:"request" yaml:"request"`
+	Response CassetteResponse `json:"response" yaml:"response"`
+}
+
+// Cassette represents the recorded interactions
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// RecorderConfig represents the config of Recorder.
+// The cassette is a YAML (.yml, .yaml) or JSON file, the secrets of the requests & responses are redacted
+// by the RedactionPolicy (the default policy when it's nil) and the Secrets before they're recorded.
+type RecorderConfig struct {
+	CassettePath    string
+	Mode            RecorderMode
+	RedactionPolicy *RedactionPolicy
+	Secrets         []string
+	Transport       http.RoundTripper
+}
+
+// Recorder is the http.RoundTripper recording the interactions into the cassette or replaying them,
+// the requests are matched on the method, url and normalised body and every interaction is replayed once in order
+type Recorder struct {
+	mu        sync.Mutex
+	config    RecorderConfig
+	redactor  *redactor
+	cassette  *Cassette
+	isUsed    []bool
+	unmatched []string
+}
+
+// NewRecorder creates the recorder, the cassette must exist in replay mode
+func NewRecorder(config RecorderConfig) (*Recorder, error) {
+	if config.Mode == "" {
+		config.Mode = ModeReplay
+	}
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+
+	recorder := &Recorder{
+		config:   config,
+		redactor: newRedactor(config.RedactionPolicy, config.Secrets),
+		cassette: &Cassette{},
+	}
+	if config.Mode == ModeReplay {
+		cassette, err := LoadCassette(config.CassettePath)
+		if err != nil {
+			return nil, err
+		}
+		recorder.cassette = cassette
+		recorder.isUsed = make([]bool, len(cassette.Interactions))
+	}
+	return recorder, nil
+}
+
+// LoadCassette reads the YAML (.yml, .yaml) or JSON cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cassette := &Cassette{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(content, cassette)
+	default:
+		err = json.Unmarshal(content, cassette)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error when reading %s: %v", path, err)
+	}
+	return cassette, nil
+}
+
+// Save writes the cassette into the YAML (.yml, .yaml) or JSON file
+func (c *Cassette) Save(path string) error {
+	var content []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		content, err = yaml.Marshal(c)
+	default:
+		content, err = json.MarshalIndent(c, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// normalizeBody returns the redacted body with the sorted keys of json & form bodies
+func (r *Recorder) normalizeBody(contentType string, body []byte) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ""
+	}
+
+	var value interface{}
+	if json.Unmarshal(body, &value) == nil {
+		normalized, err := json.Marshal(r.redactor.value(value))
+		if err == nil {
+			return r.redactor.String(string(normalized))
+		}
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			return r.redactor.Form(values.Encode())
+		}
+	}
+	return r.redactor.String(string(body))
+}
+
+// request returns the redacted & normalised request to be recorded or matched
+func (r *Recorder) request(req *http.Request, body []byte) CassetteRequest {
+	requestURL := r.redactor.URL(req.URL.String())
+	if urlPath, err := url.Parse(requestURL); err == nil {
+		// the query params are sorted
+		urlPath.RawQuery = urlPath.Query().Encode()
+		requestURL = urlPath.String()
+	}
+
+	return CassetteRequest{
+		Method: req.Method,
+		URL:    requestURL,
+		Header: r.redactor.Header(req.Header),
+		Body:   r.normalizeBody(req.Header.Get("Content-Type"), body),
+	}
+}
+
+func (request CassetteRequest) matches(other CassetteRequest) bool {
+	return request.Method == other.Method && request.URL == other.URL && request.Body == other.Body
+}
+
+// RoundTrip records or replays the request according to the mode
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	if r.config.Mode == ModeRecord {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	res, err := r.config.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+
+	// the length of the redacted body differs
+	header := r.redactor.Header(res.Header)
+	header.Del("Content-Length")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: r.request(req, body),
+		Response: CassetteResponse{
+			StatusCode: res.StatusCode,
+			Header:     header,
+			Body:       r.redactor.JSON(string(resBody)),
+		},
+	})
+	if err = r.cassette.Save(r.config.CassettePath); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	request := r.request(req, body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		if r.isUsed[i] || !interaction.Request.matches(request) {
+			continue
+		}
+		r.isUsed[i] = true
+
+		header := http.Header{}
+		for key, values := range interaction.Response.Header {
+			header[key] = append([]string{}, values...)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	unmatched := fmt.Sprintf("%s %s %s", request.Method, request.URL, request.Body)
+	r.unmatched = append(r.unmatched, unmatched)
+	log.Printf("[Recorder] Unmatched request of the cassette %s: %s", r.config.CassettePath, unmatched)
+	return nil, fmt.Errorf("%v: %s", ErrUnmatchedInteraction, unmatched)
+}
+
+// Unmatched returns the requests not matching any interaction in replay mode
+func (r *Recorder) Unmatched() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.unmatched...)
+}
+
+// Unused returns the interactions of the cassette that haven't been replayed
+func (r *Recorder) Unused() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unused := []*Interaction{}
+	for i, interaction := range r.cassette.Interactions {
+		if i < len(r.isUsed) && !r.isUsed[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+// Client returns the http client sending the requests through the recorder, it's set as HTTPClient.HTTPClient
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r, Timeout: defaultHTTPTimeout}
+}
@@ -0,0 +1,286 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/payfazz/commerce-kit/types"
+)
+
+const defaultRedactionMask = "[REDACTED]"
+
+// RedactionPolicy represents the secrets masked before the client requests are persisted or logged.
+// Headers & QueryParams are matched case-insensitively, BodyPaths are the dot separated paths of the json bodies
+// where "*" matches any key (the arrays are traversed transparently).
+// RedactTokens masks the tokens of the AuthorizationTypes of the client wherever they appear in the url & headers.
+type RedactionPolicy struct {
+	Headers      []string
+	QueryParams  []string
+	BodyPaths    []string
+	RedactTokens bool
+	Mask         string
+}
+
+// DefaultRedactionPolicy returns the policy masking the headers & query params of the built-in AuthorizationTypes
+func DefaultRedactionPolicy() *RedactionPolicy {
+	headers := []string{"Proxy-Authorization", "Cookie"}
+	for _, authorizationType := range []AuthorizationType{Basic, Bearer, AccessToken, Secret, APIKey} {
+		isExist := false
+		for _, header := range headers {
+			if strings.EqualFold(header, authorizationType.HeaderName) {
+				isExist = true
+			}
+		}
+		if !isExist {
+			headers = append(headers, authorizationType.HeaderName)
+		}
+	}
+
+	return &RedactionPolicy{
+		Headers:      headers,
+		QueryParams:  []string{APIKey.HeaderName, "api_key"},
+		RedactTokens: true,
+		Mask:         defaultRedactionMask,
+	}
+}
+
+// redactor applies the redaction policy with the tokens of the client
+type redactor struct {
+	policy *RedactionPolicy
+	mask   string
+	tokens []string
+}
+
+// newRedactor creates the redactor of the policy masking the tokens, the default policy is used when it's nil
+func newRedactor(policy *RedactionPolicy, tokens []string) *redactor {
+	if policy == nil {
+		policy = DefaultRedactionPolicy()
+	}
+
+	r := &redactor{policy: policy, mask: policy.Mask}
+	if r.mask == "" {
+		r.mask = defaultRedactionMask
+	}
+	if policy.RedactTokens {
+		for _, token := range tokens {
+			if token != "" {
+				r.tokens = append(r.tokens, token)
+			}
+		}
+	}
+	return r
+}
+
+// redactor returns the redactor of the redaction policy of the client, the default policy is used when it's not configured
+func (c *HTTPClient) redactor() *redactor {
+	tokens := []string{}
+	for _, authorizationType := range c.authorizationTypes() {
+		tokens = append(tokens, authorizationType.Token)
+	}
+	return newRedactor(c.RedactionPolicy, tokens)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks the tokens in the string
+func (r *redactor) String(value string) string {
+	for _, token := range r.tokens {
+		value = strings.Replace(value, token, r.mask, -1)
+	}
+	return value
+}
+
+// Header returns the copy of the header with the redacted values
+func (r *redactor) Header(header http.Header) http.Header {
+	redacted := http.Header{}
+	for key, values := range header {
+		for _, value := range values {
+			if containsFold(r.policy.Headers, key) {
+				value = r.mask
+			}
+			redacted.Add(key, r.String(value))
+		}
+	}
+	return redacted
+}
+
+// HeaderString masks the headers of the policy in the header formatted as JSON or by fmt ("map[Key:[value]]")
+func (r *redactor) HeaderString(header string) string {
+	jsonHeader := http.Header{}
+	if err := json.Unmarshal([]byte(header), &jsonHeader); err == nil {
+		redacted, err := json.Marshal(r.Header(jsonHeader))
+		if err == nil {
+			return string(redacted)
+		}
+	}
+
+	values := map[string]string{}
+	if err := json.Unmarshal([]byte(header), &values); err == nil {
+		for key := range values {
+			if containsFold(r.policy.Headers, key) {
+				values[key] = r.mask
+			}
+		}
+		redacted, err := json.Marshal(values)
+		if err == nil {
+			return r.String(string(redacted))
+		}
+	}
+
+	for _, name := range r.policy.Headers {
+		pattern := regexp.MustCompile(`(?i)(^|[\s\[])(` + regexp.QuoteMeta(name) + `):\[[^\]]*\]`)
+		header = pattern.ReplaceAllString(header, "${1}${2}:["+r.mask+"]")
+	}
+	return r.String(header)
+}
+
+// URL masks the query params of the url
+func (r *redactor) URL(rawURL string) string {
+	urlPath, err := url.Parse(rawURL)
+	if err != nil {
+		return r.String(rawURL)
+	}
+
+	query := urlPath.Query()
+	isRedacted := false
+	for key, values := range query {
+		for i, value := range values {
+			if containsFold(r.policy.QueryParams, key) || r.String(value) != value {
+				values[i] = r.mask
+				isRedacted = true
+			}
+		}
+	}
+	if isRedacted {
+		urlPath.RawQuery = query.Encode()
+	}
+	return r.String(urlPath.String())
+}
+
+// Form masks the query params & the top level body paths of the form body
+func (r *redactor) Form(body string) string {
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return r.String(body)
+	}
+
+	isRedacted := false
+	for key, value := range values {
+		for i, v := range value {
+			if containsFold(r.policy.QueryParams, key) || containsFold(r.policy.BodyPaths, key) || r.String(v) != v {
+				value[i] = r.mask
+				isRedacted = true
+			}
+		}
+	}
+	if !isRedacted {
+		return body
+	}
+	return values.Encode()
+}
+
+// redactPath masks the value of the path segments
+func (r *redactor) redactPath(value interface{}, segments []string) interface{} {
+	if len(segments) == 0 {
+		return r.mask
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if segments[0] == "*" || key == segments[0] {
+				v[key] = r.redactPath(child, segments[1:])
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if segments[0] == "*" {
+				v[i] = r.redactPath(child, segments[1:])
+			} else {
+				v[i] = r.redactPath(child, segments)
+			}
+		}
+	}
+	return value
+}
+
+// value returns the redacted copy of the decoded json value
+func (r *redactor) value(value interface{}) interface{} {
+	if len(r.policy.BodyPaths) == 0 {
+		return value
+	}
+
+	// deep copy so the value sent or acknowledged isn't affected
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var redacted interface{}
+	if err = json.Unmarshal(valueBytes, &redacted); err != nil {
+		return value
+	}
+
+	for _, path := range r.policy.BodyPaths {
+		redacted = r.redactPath(redacted, strings.Split(path, "."))
+	}
+	return redacted
+}
+
+// Metadata returns the redacted copy of the json body
+func (r *redactor) Metadata(body types.Metadata) types.Metadata {
+	if body == nil {
+		return nil
+	}
+	if redacted, ok := r.value(map[string]interface{}(body)).(map[string]interface{}); ok {
+		return redacted
+	}
+	return body
+}
+
+// JSON returns the redacted json string, the non json string only has its tokens masked
+func (r *redactor) JSON(body string) string {
+	var value interface{}
+	if len(r.policy.BodyPaths) == 0 || json.Unmarshal([]byte(body), &value) != nil {
+		return r.String(body)
+	}
+
+	redacted, err := json.Marshal(r.value(value))
+	if err != nil {
+		return r.String(body)
+	}
+	return r.String(string(redacted))
+}
+
+// ClientRequestLog returns the redacted copy of the log to be persisted, the response is redacted by the format of the decoder
+func (r *redactor) ClientRequestLog(clientRequestLog *ClientRequestLog, decoder ResponseDecoder) *ClientRequestLog {
+	redacted := *clientRequestLog
+	redacted.URL = r.URL(clientRequestLog.URL)
+	redacted.Request = r.Metadata(clientRequestLog.Request)
+	redacted.Response = r.logResponse(decoder, clientRequestLog.Response)
+
+	if attempts, ok := clientRequestLog.Metadata["attempts"].([]*ClientRequestAttempt); ok {
+		redactedAttempts := []*ClientRequestAttempt{}
+		for _, attempt := range attempts {
+			redactedAttempt := *attempt
+			redactedAttempt.Error = r.String(strings.Replace(attempt.Error, clientRequestLog.URL, redacted.URL, -1))
+			redactedAttempts = append(redactedAttempts, &redactedAttempt)
+		}
+
+		redacted.Metadata = types.Metadata{}
+		for key, value := range clientRequestLog.Metadata {
+			redacted.Metadata[key] = value
+		}
+		redacted.Metadata["attempts"] = redactedAttempts
+	}
+	return &redacted
+}
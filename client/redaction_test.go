@@ -0,0 +1,295 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/payfazz/commerce-kit/types"
+)
+
+func TestRedactorHeader(t *testing.T) {
+	r := newRedactor(nil, []string{"s3cr3t"})
+	header := http.Header{
+		"Authorization":  {"Bearer s3cr3t"},
+		"X-Access-Token": {"token"},
+		"X-Trace":        {"trace s3cr3t"},
+		"Accept":         {"application/json"},
+	}
+
+	redacted := r.Header(header)
+	want := http.Header{
+		"Authorization":  {defaultRedactionMask},
+		"X-Access-Token": {defaultRedactionMask},
+		"X-Trace":        {"trace " + defaultRedactionMask},
+		"Accept":         {"application/json"},
+	}
+	if !reflect.DeepEqual(redacted, want) {
+		t.Errorf("Header() = %v, want %v", redacted, want)
+	}
+	if header.Get("Authorization") != "Bearer s3cr3t" {
+		t.Errorf("Header() mutated the original header: %v", header)
+	}
+}
+
+func TestRedactorHeaderString(t *testing.T) {
+	r := newRedactor(nil, []string{"s3cr3t"})
+	header := http.Header{
+		"Authorization": {"Bearer s3cr3t"},
+		"Cookie":        {"session=abc"},
+		"Accept":        {"application/json"},
+	}
+	jsonHeader, _ := json.Marshal(header)
+
+	tests := []struct {
+		name   string
+		header string
+		masked []string
+		kept   []string
+	}{
+		{
+			name:   "fmt",
+			header: fmt.Sprintf("%v", header),
+			masked: []string{"Authorization:[" + defaultRedactionMask + "]", "Cookie:[" + defaultRedactionMask + "]"},
+			kept:   []string{"Accept:[application/json]"},
+		},
+		{
+			name:   "fmt lower case",
+			header: "map[authorization:[Bearer s3cr3t] accept:[text/plain]]",
+			masked: []string{"authorization:[" + defaultRedactionMask + "]"},
+			kept:   []string{"accept:[text/plain]"},
+		},
+		{
+			name:   "json header",
+			header: string(jsonHeader),
+			masked: []string{`"Authorization":["` + defaultRedactionMask + `"]`, `"Cookie":["` + defaultRedactionMask + `"]`},
+			kept:   []string{`"Accept":["application/json"]`},
+		},
+		{
+			name:   "json map",
+			header: `{"Authorization":"Bearer s3cr3t","Accept":"application/json"}`,
+			masked: []string{`"Authorization":"` + defaultRedactionMask + `"`},
+			kept:   []string{`"Accept":"application/json"`},
+		},
+		{
+			name:   "token only",
+			header: "X-Trace: s3cr3t",
+			masked: []string{"X-Trace: " + defaultRedactionMask},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redacted := r.HeaderString(tt.header)
+			if strings.Contains(redacted, "s3cr3t") || strings.Contains(redacted, "session=abc") {
+				t.Errorf("HeaderString() = %q, want the secrets masked", redacted)
+			}
+			for _, masked := range tt.masked {
+				if !strings.Contains(redacted, masked) {
+					t.Errorf("HeaderString() = %q, want it to contain %q", redacted, masked)
+				}
+			}
+			for _, kept := range tt.kept {
+				if !strings.Contains(redacted, kept) {
+					t.Errorf("HeaderString() = %q, want it to keep %q", redacted, kept)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactorURL(t *testing.T) {
+	r := newRedactor(nil, []string{"s3cr3t"})
+	tests := []struct {
+		name   string
+		url    string
+		masked []string
+		kept   []string
+	}{
+		{"api key param", "https://api.example.com/orders?APIKey=k1&page=2", []string{"APIKey"}, []string{"page"}},
+		{"api_key param", "https://api.example.com/orders?api_key=k2&page=2", []string{"api_key"}, []string{"page"}},
+		{"case insensitive", "https://api.example.com/orders?apikey=k3", []string{"apikey"}, nil},
+		{"token value", "https://api.example.com/orders?sig=s3cr3t&page=2", []string{"sig"}, []string{"page"}},
+		{"no query", "https://api.example.com/orders/1", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redacted, err := url.Parse(r.URL(tt.url))
+			if err != nil {
+				t.Fatalf("URL() isn't a url: %v", err)
+			}
+			original, _ := url.Parse(tt.url)
+			if redacted.Path != original.Path {
+				t.Errorf("URL() path = %q, want %q", redacted.Path, original.Path)
+			}
+
+			query := redacted.Query()
+			for _, key := range tt.masked {
+				if query.Get(key) != defaultRedactionMask {
+					t.Errorf("URL() %s = %q, want it masked", key, query.Get(key))
+				}
+			}
+			for _, key := range tt.kept {
+				if query.Get(key) != original.Query().Get(key) {
+					t.Errorf("URL() %s = %q, want %q", key, query.Get(key), original.Query().Get(key))
+				}
+			}
+		})
+	}
+}
+
+func TestRedactorBodyPaths(t *testing.T) {
+	body := `{
+		"password": "p",
+		"name": "n",
+		"user": {"secret": "s", "name": "u"},
+		"cards": [{"number": "4111", "holder": "h"}, {"number": "5500", "holder": "i"}],
+		"items": [{"pin": "1"}, {"pin": "2"}]
+	}`
+
+	tests := []struct {
+		name  string
+		paths []string
+		want  map[string]interface{}
+	}{
+		{
+			name:  "top level",
+			paths: []string{"password"},
+			want: map[string]interface{}{
+				"password": defaultRedactionMask,
+				"name":     "n",
+			},
+		},
+		{
+			name:  "wildcard key",
+			paths: []string{"*.secret"},
+			want: map[string]interface{}{
+				"password": "p",
+				"user":     map[string]interface{}{"secret": defaultRedactionMask, "name": "u"},
+			},
+		},
+		{
+			name:  "wildcard array",
+			paths: []string{"cards.*.number"},
+			want: map[string]interface{}{
+				"cards": []interface{}{
+					map[string]interface{}{"number": defaultRedactionMask, "holder": "h"},
+					map[string]interface{}{"number": defaultRedactionMask, "holder": "i"},
+				},
+			},
+		},
+		{
+			name:  "transparent array",
+			paths: []string{"items.pin"},
+			want: map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"pin": defaultRedactionMask},
+					map[string]interface{}{"pin": defaultRedactionMask},
+				},
+			},
+		},
+		{
+			name:  "missing path",
+			paths: []string{"user.password"},
+			want: map[string]interface{}{
+				"user": map[string]interface{}{"secret": "s", "name": "u"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRedactor(&RedactionPolicy{BodyPaths: tt.paths}, nil)
+			redacted := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(r.JSON(body)), &redacted); err != nil {
+				t.Fatalf("JSON() isn't json: %v", err)
+			}
+			for key, want := range tt.want {
+				if !reflect.DeepEqual(redacted[key], want) {
+					t.Errorf("JSON() %s = %v, want %v", key, redacted[key], want)
+				}
+			}
+
+			metadata := types.Metadata{}
+			json.Unmarshal([]byte(body), &metadata)
+			redactedMetadata := r.Metadata(metadata)
+			for key, want := range tt.want {
+				if !reflect.DeepEqual(redactedMetadata[key], want) {
+					t.Errorf("Metadata() %s = %v, want %v", key, redactedMetadata[key], want)
+				}
+			}
+			if metadata["password"] != "p" {
+				t.Errorf("Metadata() mutated the original body: %v", metadata)
+			}
+		})
+	}
+}
+
+func TestRedactorForm(t *testing.T) {
+	r := newRedactor(&RedactionPolicy{QueryParams: []string{"api_key"}, BodyPaths: []string{"password"}, RedactTokens: true}, []string{"s3cr3t"})
+	tests := []struct {
+		name string
+		body string
+		want url.Values
+	}{
+		{"body path", "user=a&password=p", url.Values{"user": {"a"}, "password": {defaultRedactionMask}}},
+		{"query param", "api_key=k&page=1", url.Values{"api_key": {defaultRedactionMask}, "page": {"1"}}},
+		{"token value", "note=s3cr3t", url.Values{"note": {defaultRedactionMask}}},
+		{"untouched", "user=a", url.Values{"user": {"a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redacted, err := url.ParseQuery(r.Form(tt.body))
+			if err != nil {
+				t.Fatalf("Form() isn't a form: %v", err)
+			}
+			if !reflect.DeepEqual(redacted, tt.want) {
+				t.Errorf("Form() = %v, want %v", redacted, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorClientRequestLog(t *testing.T) {
+	r := newRedactor(&RedactionPolicy{QueryParams: []string{"APIKey"}, BodyPaths: []string{"password"}}, nil)
+	rawURL := "https://api.example.com/login?APIKey=k1"
+	attempts := []*ClientRequestAttempt{{Attempt: 1, Error: "Post " + rawURL + ": timeout"}}
+	original := &ClientRequestLog{
+		URL:      rawURL,
+		Request:  types.Metadata{"user": "a", "password": "p"},
+		Response: `{"token":"t","password":"p"}`,
+		Metadata: types.Metadata{"attempts": attempts, "rateLimitWaitMs": 10},
+	}
+
+	redacted := r.ClientRequestLog(original, JSONDecoder)
+
+	if original.URL != rawURL || original.Request["password"] != "p" || original.Response != `{"token":"t","password":"p"}` {
+		t.Errorf("ClientRequestLog() mutated the original log: %+v", original)
+	}
+	if attempts[0].Error != "Post "+rawURL+": timeout" || original.Metadata["attempts"].([]*ClientRequestAttempt)[0] != attempts[0] {
+		t.Errorf("ClientRequestLog() mutated the original attempts: %+v", attempts[0])
+	}
+
+	if strings.Contains(redacted.URL, "k1") {
+		t.Errorf("ClientRequestLog() url = %q, want the api key masked", redacted.URL)
+	}
+	if redacted.Request["password"] != defaultRedactionMask || redacted.Request["user"] != "a" {
+		t.Errorf("ClientRequestLog() request = %v, want the password masked", redacted.Request)
+	}
+	if strings.Contains(redacted.Response, `"p"`) {
+		t.Errorf("ClientRequestLog() response = %q, want the password masked", redacted.Response)
+	}
+	redactedAttempts := redacted.Metadata["attempts"].([]*ClientRequestAttempt)
+	if strings.Contains(redactedAttempts[0].Error, "k1") {
+		t.Errorf("ClientRequestLog() attempt error = %q, want the api key masked", redactedAttempts[0].Error)
+	}
+	if redacted.Metadata["rateLimitWaitMs"] != 10 {
+		t.Errorf("ClientRequestLog() metadata = %v, want the other metadata kept", redacted.Metadata)
+	}
+}
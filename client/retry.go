@@ -0,0 +1,164 @@
+package client
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HeaderIdempotencyKey is the request header holding the idempotency key,
+// the request having it is retried regardless of its method
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// DefaultRetryStatusCodes are the response status codes retried by default
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy represents the policy of retrying a failed request.
+// The transport errors and the responses of StatusCodes are retried up to MaxAttempts (including the first attempt)
+// while the elapsed time is within MaxElapsedTime, zero MaxElapsedTime means unlimited.
+// The Retry-After of the response is followed up to MaxDelay and the wait stops when the call's context is done.
+// Only the idempotent methods are retried unless the request has an idempotency key or RetryNonIdempotent is true.
+type RetryPolicy struct {
+	MaxAttempts        int
+	MaxElapsedTime     time.Duration
+	StatusCodes        []int
+	MinDelay           time.Duration
+	MaxDelay           time.Duration
+	RetryNonIdempotent bool
+}
+
+// ClientRequestAttempt represents a single attempt of a client request, it's recorded in the client request log
+type ClientRequestAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"statusCode"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	DelayMs    int64     `json:"delayMs"`
+}
+
+// retryPolicy returns the retry policy of the client, it's derived from MaxNetworkRetries when it's not configured
+func (c *HTTPClient) retryPolicy() *RetryPolicy {
+	if c.RetryPolicy != nil {
+		return withRetryDefaults(*c.RetryPolicy)
+	}
+	return withRetryDefaults(RetryPolicy{MaxAttempts: c.MaxNetworkRetries + 1})
+}
+
+// withRetryDefaults fills the unset values of the policy with the defaults
+func withRetryDefaults(policy RetryPolicy) *RetryPolicy {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+	if policy.StatusCodes == nil {
+		policy.StatusCodes = DefaultRetryStatusCodes
+	}
+	if policy.MinDelay <= 0 {
+		policy.MinDelay = minNetworkRetriesDelay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = maxNetworkRetriesDelay
+	}
+	return &policy
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isRetryable returns true when the request is allowed to be sent more than once
+func (p *RetryPolicy) isRetryable(req *http.Request) bool {
+	if p.RetryNonIdempotent || isIdempotentMethod(req.Method) || req.Header.Get(HeaderIdempotencyKey) != "" {
+		return true
+	}
+	return false
+}
+
+func (p *RetryPolicy) isRetryStatusCode(statusCode int) bool {
+	for _, code := range p.StatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header given in seconds or as a http date
+func retryAfter(res *http.Response) time.Duration {
+	if res == nil {
+		return 0
+	}
+
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// backoff returns the delay before the next attempt, exponentially backoff by 2^numOfRetries with jitter
+func (c *HTTPClient) backoff(policy *RetryPolicy, numRetries int) time.Duration {
+	if c.UseNormalSleep {
+		return 0
+	}
+
+	delay := policy.MinDelay + policy.MinDelay*time.Duration(1<<uint(numRetries))
+	if delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+
+	// generate random jitter to prevent thundering herd problem
+	if delay/4 > 0 {
+		jitter := rand.Int63n(int64(delay / 4))
+		delay -= time.Duration(jitter)
+	}
+
+	if delay < policy.MinDelay {
+		delay = policy.MinDelay
+	}
+
+	return delay
+}
+
+// nextAttempt returns whether the request should be retried and the delay before retrying it
+func (c *HTTPClient) nextAttempt(policy *RetryPolicy, req *http.Request, res *http.Response, err error, attempt int, startedAt time.Time) (bool, time.Duration) {
+	if attempt >= policy.MaxAttempts || !policy.isRetryable(req) {
+		return false, 0
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false, 0
+	}
+	if err == nil && !policy.isRetryStatusCode(res.StatusCode) {
+		return false, 0
+	}
+
+	// the Retry-After of the server is followed up to the MaxDelay
+	delay := c.backoff(policy, attempt-1)
+	if after := retryAfter(res); after > delay {
+		delay = after
+		if delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+
+	if policy.MaxElapsedTime > 0 && time.Since(startedAt)+delay > policy.MaxElapsedTime {
+		return false, 0
+	}
+	return true, delay
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	c := &HTTPClient{}
+	policy := withRetryDefaults(RetryPolicy{MinDelay: 100 * time.Millisecond, MaxDelay: time.Second})
+
+	tests := []struct {
+		numRetries int
+		base       time.Duration
+	}{
+		{0, 200 * time.Millisecond},
+		{1, 300 * time.Millisecond},
+		{2, 500 * time.Millisecond},
+		{3, 900 * time.Millisecond},
+		{4, time.Second},
+		{10, time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			delay := c.backoff(policy, tt.numRetries)
+			if delay > tt.base || delay < tt.base*3/4 || delay < policy.MinDelay {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", tt.numRetries, delay, tt.base*3/4, tt.base)
+			}
+		}
+	}
+
+	if delay := (&HTTPClient{UseNormalSleep: true}).backoff(policy, 3); delay != 0 {
+		t.Errorf("backoff() with UseNormalSleep = %v, want 0", delay)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"none", "", 0, 0},
+		{"seconds", "3", 3 * time.Second, 3 * time.Second},
+		{"zero", "0", 0, 0},
+		{"negative", "-1", 0, 0},
+		{"invalid", "soon", 0, 0},
+		{"http date", time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), 8 * time.Second, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			if tt.value != "" {
+				res.Header.Set("Retry-After", tt.value)
+			}
+			if after := retryAfter(res); after < tt.min || after > tt.max {
+				t.Errorf("retryAfter(%q) = %v, want within [%v, %v]", tt.value, after, tt.min, tt.max)
+			}
+		})
+	}
+
+	if after := retryAfter(nil); after != 0 {
+		t.Errorf("retryAfter(nil) = %v, want 0", after)
+	}
+}
+
+func TestNextAttempt(t *testing.T) {
+	c := &HTTPClient{}
+	policy := withRetryDefaults(RetryPolicy{MaxAttempts: 3, MinDelay: time.Millisecond, MaxDelay: 2 * time.Second})
+	response := func(statusCode int, retryAfter string) *http.Response {
+		res := &http.Response{StatusCode: statusCode, Header: http.Header{}}
+		if retryAfter != "" {
+			res.Header.Set("Retry-After", retryAfter)
+		}
+		return res
+	}
+	request := func(method string, idempotencyKey string) *http.Request {
+		req, _ := http.NewRequest(method, "http://example.com", nil)
+		if idempotencyKey != "" {
+			req.Header.Set(HeaderIdempotencyKey, idempotencyKey)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name      string
+		req       *http.Request
+		res       *http.Response
+		attempt   int
+		startedAt time.Time
+		isRetry   bool
+		minDelay  time.Duration
+		maxDelay  time.Duration
+	}{
+		{"retried status", request(http.MethodGet, ""), response(http.StatusServiceUnavailable, ""), 1, time.Now(), true, time.Millisecond, 2 * time.Millisecond},
+		{"retry after", request(http.MethodGet, ""), response(http.StatusTooManyRequests, "1"), 1, time.Now(), true, time.Second, time.Second},
+		{"retry after capped", request(http.MethodGet, ""), response(http.StatusTooManyRequests, "60"), 1, time.Now(), true, 2 * time.Second, 2 * time.Second},
+		{"not retried status", request(http.MethodGet, ""), response(http.StatusBadRequest, ""), 1, time.Now(), false, 0, 0},
+		{"non idempotent", request(http.MethodPost, ""), response(http.StatusServiceUnavailable, ""), 1, time.Now(), false, 0, 0},
+		{"idempotency key", request(http.MethodPost, "key"), response(http.StatusServiceUnavailable, ""), 1, time.Now(), true, time.Millisecond, 2 * time.Millisecond},
+		{"max attempts", request(http.MethodGet, ""), response(http.StatusServiceUnavailable, ""), 3, time.Now(), false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isRetry, delay := c.nextAttempt(policy, tt.req, tt.res, nil, tt.attempt, tt.startedAt)
+			if isRetry != tt.isRetry || delay < tt.minDelay || delay > tt.maxDelay {
+				t.Errorf("nextAttempt() = %v, %v, want %v within [%v, %v]", isRetry, delay, tt.isRetry, tt.minDelay, tt.maxDelay)
+			}
+		})
+	}
+
+	elapsedPolicy := withRetryDefaults(RetryPolicy{MaxAttempts: 3, MaxElapsedTime: time.Second, MaxDelay: 2 * time.Second})
+	if isRetry, _ := c.nextAttempt(elapsedPolicy, request(http.MethodGet, ""), response(http.StatusTooManyRequests, "2"), nil, 1, time.Now()); isRetry {
+		t.Error("nextAttempt() beyond MaxElapsedTime = true, want false")
+	}
+}
+
+func TestRoundTripWaitsRetryAfter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	c := &HTTPClient{HTTPClient: server.Client()}
+	policy := withRetryDefaults(RetryPolicy{MaxAttempts: 2, MinDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond})
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+
+	attempts := []*ClientRequestAttempt{}
+	startedAt := time.Now()
+	_, body, errResponse := c.roundTrip(req, policy, c.errorDecoder(), false, nil, func(attempt *ClientRequestAttempt) {
+		attempts = append(attempts, attempt)
+	})
+	if isCallFailed(errResponse) || string(body) != `{"ok":true}` {
+		t.Fatalf("roundTrip() = %s, %+v, want the retried response", body, errResponse)
+	}
+	if elapsed := time.Since(startedAt); elapsed < 50*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("roundTrip() took %v, want the Retry-After capped at MaxDelay", elapsed)
+	}
+	if len(attempts) != 2 || attempts[0].StatusCode != http.StatusServiceUnavailable || attempts[0].DelayMs != 50 {
+		t.Errorf("roundTrip() attempts = %+v, want the delayed 503 then the 200", attempts)
+	}
+}
+
+func TestRoundTripStopsWaitingOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := &HTTPClient{HTTPClient: server.Client()}
+	policy := withRetryDefaults(RetryPolicy{MaxAttempts: 3, MaxDelay: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req = req.WithContext(ctx)
+
+	startedAt := time.Now()
+	_, _, errResponse := c.roundTrip(req, policy, c.errorDecoder(), false, nil, nil)
+	if errResponse == nil || errResponse.Error != context.DeadlineExceeded {
+		t.Errorf("roundTrip() error = %+v, want the deadline exceeded", errResponse)
+	}
+	if elapsed := time.Since(startedAt); elapsed > 5*time.Second {
+		t.Errorf("roundTrip() took %v, want it to stop waiting when the context is done", elapsed)
+	}
+}
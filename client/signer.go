@@ -0,0 +1,133 @@
+package client
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// RequestSigner signs the request with its finalised body, it's called on every attempt of the request
+type RequestSigner interface {
+	Sign(req *http.Request, body []byte) error
+}
+
+// Components of the canonical string of HMACSigner, the "header:<name>" component takes the value of the header
+const (
+	SignMethod     = "method"
+	SignPath       = "path"
+	SignQuery      = "query"
+	SignTimestamp  = "timestamp"
+	SignBody       = "body"
+	SignBodySHA256 = "bodySHA256"
+)
+
+// HMACSigner signs the request by the HMAC-SHA256 of the canonical string built from the Components joined by the Separator.
+// The signature is hex encoded unless Base64 is true, the timestamp is the unix seconds unless TimestampFormat is given.
+type HMACSigner struct {
+	Secret          []byte
+	KeyID           string
+	Components      []string
+	Separator       string
+	SignatureHeader string
+	TimestampHeader string
+	KeyIDHeader     string
+	Base64          bool
+	TimestampFormat func(t time.Time) string
+}
+
+// canonicalString builds the string to be signed of the request
+func (s *HMACSigner) canonicalString(req *http.Request, body []byte, timestamp string) string {
+	values := []string{}
+	for _, component := range s.Components {
+		switch {
+		case component == SignMethod:
+			values = append(values, req.Method)
+		case component == SignPath:
+			values = append(values, req.URL.EscapedPath())
+		case component == SignQuery:
+			values = append(values, req.URL.Query().Encode())
+		case component == SignTimestamp:
+			values = append(values, timestamp)
+		case component == SignBody:
+			values = append(values, string(body))
+		case component == SignBodySHA256:
+			hash := sha256.Sum256(body)
+			values = append(values, hex.EncodeToString(hash[:]))
+		case strings.HasPrefix(component, "header:"):
+			values = append(values, req.Header.Get(strings.TrimPrefix(component, "header:")))
+		}
+	}
+	return strings.Join(values, s.Separator)
+}
+
+// Sign sets the timestamp, key id & signature headers of the request
+func (s *HMACSigner) Sign(req *http.Request, body []byte) error {
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	if s.TimestampFormat != nil {
+		timestamp = s.TimestampFormat(now)
+	}
+	req.Header.Set(s.TimestampHeader, timestamp)
+	if s.KeyID != "" {
+		req.Header.Set(s.KeyIDHeader, s.KeyID)
+	}
+
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(s.canonicalString(req, body, timestamp)))
+	sum := mac.Sum(nil)
+	signature := hex.EncodeToString(sum)
+	if s.Base64 {
+		signature = base64.StdEncoding.EncodeToString(sum)
+	}
+	req.Header.Set(s.SignatureHeader, signature)
+
+	return nil
+}
+
+// NewHMACSigner creates the HMAC signer signing the method, path, timestamp & body
+// into the X-Signature header with the timestamp in the X-Timestamp header
+func NewHMACSigner(secret string, keyID string) *HMACSigner {
+	return &HMACSigner{
+		Secret:          []byte(secret),
+		KeyID:           keyID,
+		Components:      []string{SignMethod, SignPath, SignTimestamp, SignBody},
+		Separator:       "\n",
+		SignatureHeader: "X-Signature",
+		TimestampHeader: "X-Timestamp",
+		KeyIDHeader:     "X-Key-Id",
+	}
+}
+
+// AWSV4Signer signs the request by the AWS signature version 4
+type AWSV4Signer struct {
+	signer  *v4.Signer
+	service string
+	region  string
+}
+
+// Sign sets the AWS signature version 4 headers of the request
+func (s *AWSV4Signer) Sign(req *http.Request, body []byte) error {
+	_, err := s.signer.Sign(req, bytes.NewReader(body), s.service, s.region, time.Now())
+	return err
+}
+
+// NewAWSV4Signer creates the AWS signature version 4 signer of the service & region by the static credentials
+func NewAWSV4Signer(accessKeyID string, secretAccessKey string, sessionToken string, service string, region string) *AWSV4Signer {
+	return &AWSV4Signer{
+		signer: v4.NewSigner(credentials.NewStaticCredentials(accessKeyID, secretAccessKey, sessionToken), func(signer *v4.Signer) {
+			// the body of the request is set on every attempt
+			signer.DisableRequestBodyOverwrite = true
+		}),
+		service: service,
+		region:  region,
+	}
+}
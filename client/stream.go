@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Errors of the streamed response body
+var (
+	ErrSizeLimitExceeded = errors.New("response body exceeds the size limit")
+	ErrChecksumMismatch  = errors.New("response body checksum mismatch")
+)
+
+// ProgressFunc is called with the bytes read so far and the total bytes, the total is -1 when it's unknown
+type ProgressFunc func(read int64, total int64)
+
+// StreamResponse represents the response of a streamed call, its Body must be closed
+type StreamResponse struct {
+	StatusCode    int
+	Header        http.Header
+	ContentLength int64
+	Body          io.ReadCloser
+}
+
+// WithMaxSize fails the streamed call when the response body exceeds the bytes
+func WithMaxSize(maxSize int64) CallOption {
+	return func(o *callOptions) {
+		o.maxSize = maxSize
+	}
+}
+
+// WithChecksum verifies the streamed response body against the hex encoded checksum of the hash, e.g. sha256.New
+func WithChecksum(newHash func() hash.Hash, checksum string) CallOption {
+	return func(o *callOptions) {
+		o.newChecksumHash = newHash
+		o.checksum = checksum
+	}
+}
+
+// WithProgress calls the progress func whenever the streamed response body is read
+func WithProgress(progress ProgressFunc) CallOption {
+	return func(o *callOptions) {
+		o.progress = progress
+	}
+}
+
+// streamReader reads the response body enforcing the size limit & checksum of the call,
+// the call log is updated with the bytes read once the body is finished
+type streamReader struct {
+	body     io.ReadCloser
+	call     *CallRequest
+	read     int64
+	total    int64
+	hash     hash.Hash
+	isDone   bool
+	doneErr  error
+	finished func(read int64, err error)
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	if r.isDone {
+		if r.doneErr != nil {
+			return 0, r.doneErr
+		}
+		return 0, io.EOF
+	}
+
+	maxSize := r.call.options.maxSize
+	if maxSize > 0 && int64(len(p)) > maxSize-r.read+1 {
+		// read one byte more than the limit to detect the exceeded body
+		p = p[:maxSize-r.read+1]
+	}
+
+	n, err := r.body.Read(p)
+	if maxSize > 0 && r.read+int64(n) > maxSize {
+		n = int(maxSize - r.read)
+		err = ErrSizeLimitExceeded
+	}
+	r.read += int64(n)
+	if r.hash != nil {
+		r.hash.Write(p[:n])
+	}
+	if r.call.options.progress != nil && n > 0 {
+		r.call.options.progress(r.read, r.total)
+	}
+
+	if err == io.EOF && r.hash != nil && !strings.EqualFold(hex.EncodeToString(r.hash.Sum(nil)), r.call.options.checksum) {
+		err = ErrChecksumMismatch
+	}
+	if err != nil {
+		if err == io.EOF {
+			r.done(nil)
+		} else {
+			r.done(err)
+		}
+	}
+	return n, err
+}
+
+// Close closes the response body, the body closed before it's finished is logged as incomplete
+func (r *streamReader) Close() error {
+	if !r.isDone {
+		r.done(io.ErrUnexpectedEOF)
+	}
+	return r.body.Close()
+}
+
+func (r *streamReader) done(err error) {
+	r.isDone = true
+	r.doneErr = err
+	r.finished(r.read, err)
+}
+
+// finishStream records the bytes read & the result of the streamed body into the call log
+func (c *HTTPClient) finishStream(call *CallRequest) func(read int64, err error) {
+	return func(read int64, err error) {
+		if call.Log == nil || call.updateLog == nil {
+			return
+		}
+
+		call.Log.Metadata["bytesRead"] = read
+		if err != nil {
+			call.Log.Status = "failed"
+			call.Log.Metadata["streamError"] = err.Error()
+		}
+		call.updateLog()
+	}
+}
+
+// Stream calls the client through the pipeline and returns the response body as a reader instead of reading it,
+// the redis & client caches are not applied and only the metadata of the body is logged
+func (c *HTTPClient) Stream(ctx *context.Context, method Method, path string, opts ...CallOption) (*StreamResponse, *ResponseError) {
+	options := newCallOptions(opts)
+	options.isStream = true
+	options.isCaching = false
+	options.isRedisCaching = false
+
+	call, errDo := c.newCallRequest(method, path, options)
+	if errDo != nil {
+		return nil, errDo
+	}
+
+	_, errDo = c.handler(options)(ctx, call)
+	if isCallFailed(errDo) {
+		if call.Response != nil {
+			call.Response.Body.Close()
+		}
+		return nil, errDo
+	}
+	if call.Response == nil {
+		err := errors.New("the streamed call has no response")
+		return nil, &ResponseError{
+			Message: err.Error(),
+			Error:   err,
+		}
+	}
+
+	res := call.Response
+	if options.maxSize > 0 && res.ContentLength > options.maxSize {
+		res.Body.Close()
+		c.finishStream(call)(0, ErrSizeLimitExceeded)
+		return nil, &ResponseError{
+			Message:    ErrSizeLimitExceeded.Error(),
+			StatusCode: res.StatusCode,
+			Error:      ErrSizeLimitExceeded,
+		}
+	}
+
+	body := &streamReader{
+		body:     res.Body,
+		call:     call,
+		total:    res.ContentLength,
+		finished: c.finishStream(call),
+	}
+	if options.newChecksumHash != nil {
+		body.hash = options.newChecksumHash()
+	}
+
+	return &StreamResponse{
+		StatusCode:    res.StatusCode,
+		Header:        res.Header,
+		ContentLength: res.ContentLength,
+		Body:          body,
+	}, nil
+}
+
+// Download streams the response body of the GET path into the writer
+func (c *HTTPClient) Download(ctx *context.Context, path string, w io.Writer, opts ...CallOption) *ResponseError {
+	res, errDo := c.Stream(ctx, GET, path, opts...)
+	if errDo != nil {
+		return errDo
+	}
+	defer res.Body.Close()
+
+	_, err := io.Copy(w, res.Body)
+	if err != nil {
+		return &ResponseError{
+			Message:    err.Error(),
+			StatusCode: res.StatusCode,
+			Error:      err,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/payfazz/commerce-kit/types"
+)
+
+type closeRecorder struct {
+	io.Reader
+	isClosed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.isClosed = true
+	return nil
+}
+
+func newStreamReader(content string, opts ...CallOption) (*streamReader, *closeRecorder, *ClientRequestLog) {
+	body := &closeRecorder{Reader: iotest.OneByteReader(strings.NewReader(content))}
+	call := &CallRequest{
+		options:   newCallOptions(opts),
+		Log:       &ClientRequestLog{Status: "success", Metadata: types.Metadata{}},
+		updateLog: func() {},
+	}
+
+	reader := &streamReader{
+		body:     body,
+		call:     call,
+		total:    int64(len(content)),
+		finished: (&HTTPClient{}).finishStream(call),
+	}
+	if call.options.newChecksumHash != nil {
+		reader.hash = call.options.newChecksumHash()
+	}
+	return reader, body, call.Log
+}
+
+func sha256Hex(content string) string {
+	checksum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(checksum[:])
+}
+
+func TestStreamReaderLimits(t *testing.T) {
+	content := "0123456789"
+	tests := []struct {
+		name    string
+		opts    []CallOption
+		read    string
+		wantErr error
+	}{
+		{"no limit", nil, content, nil},
+		{"within the size", []CallOption{WithMaxSize(10)}, content, nil},
+		{"exceeds the size", []CallOption{WithMaxSize(4)}, "0123", ErrSizeLimitExceeded},
+		{"matching checksum", []CallOption{WithChecksum(sha256.New, sha256Hex(content))}, content, nil},
+		{"upper case checksum", []CallOption{WithChecksum(sha256.New, strings.ToUpper(sha256Hex(content)))}, content, nil},
+		{"mismatched checksum", []CallOption{WithChecksum(sha256.New, sha256Hex("other"))}, content, ErrChecksumMismatch},
+		{"size before checksum", []CallOption{WithMaxSize(4), WithChecksum(sha256.New, sha256Hex(content))}, "0123", ErrSizeLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, _, log := newStreamReader(content, tt.opts...)
+			read, err := ioutil.ReadAll(reader)
+			if err != tt.wantErr {
+				t.Fatalf("ReadAll() error = %v, want %v", err, tt.wantErr)
+			}
+			if string(read) != tt.read {
+				t.Errorf("ReadAll() = %q, want %q", read, tt.read)
+			}
+
+			// the error is kept once the body is finished
+			if _, errAgain := reader.Read(make([]byte, 1)); (tt.wantErr == nil && errAgain != io.EOF) || (tt.wantErr != nil && errAgain != tt.wantErr) {
+				t.Errorf("Read() after finishing error = %v, want %v", errAgain, tt.wantErr)
+			}
+
+			if log.Metadata["bytesRead"] != int64(len(tt.read)) {
+				t.Errorf("bytesRead = %v, want %v", log.Metadata["bytesRead"], len(tt.read))
+			}
+			if tt.wantErr != nil && (log.Status != "failed" || log.Metadata["streamError"] != tt.wantErr.Error()) {
+				t.Errorf("log = %s %v, want the failed stream", log.Status, log.Metadata)
+			}
+			if tt.wantErr == nil && log.Status != "success" {
+				t.Errorf("log status = %s, want success", log.Status)
+			}
+		})
+	}
+}
+
+func TestStreamReaderProgress(t *testing.T) {
+	progress := []int64{}
+	reader, _, _ := newStreamReader("abc", WithProgress(func(read int64, total int64) {
+		if total != 3 {
+			t.Errorf("progress total = %d, want 3", total)
+		}
+		progress = append(progress, read)
+	}))
+
+	if _, err := ioutil.ReadAll(reader); err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(progress) != 3 || progress[2] != 3 {
+		t.Errorf("progress = %v, want every byte read", progress)
+	}
+}
+
+func TestStreamReaderCloseEarly(t *testing.T) {
+	reader, body, log := newStreamReader("0123456789")
+	reader.Read(make([]byte, 4))
+
+	if err := reader.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !body.isClosed {
+		t.Error("Close() didn't close the response body")
+	}
+	if log.Status != "failed" || log.Metadata["streamError"] != io.ErrUnexpectedEOF.Error() || log.Metadata["bytesRead"] != int64(1) {
+		t.Errorf("log = %s %v, want the incomplete stream", log.Status, log.Metadata)
+	}
+}
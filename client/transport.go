@@ -0,0 +1,249 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultCertReloadInterval = time.Minute
+
+// TransportConfig represents the transport config of a client.
+// The client certificate is loaded from CertFile & KeyFile (reloaded when the files change) or from CertPEM & KeyPEM,
+// the root CAs are appended into the system pool. The zero values use the defaults of http.DefaultTransport.
+type TransportConfig struct {
+	CertFile              string
+	KeyFile               string
+	CertPEM               []byte
+	KeyPEM                []byte
+	RootCAFiles           []string
+	RootCAPEM             []byte
+	MinTLSVersion         uint16
+	ProxyURL              string
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int
+	IdleConnTimeout       time.Duration
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	Timeout               time.Duration
+	CertReloadInterval    time.Duration
+}
+
+// certReloader serves the client certificate of the files, it's reloaded when the files are modified
+type certReloader struct {
+	mu            sync.RWMutex
+	certFile      string
+	keyFile       string
+	interval      time.Duration
+	cert          *tls.Certificate
+	modTime       time.Time
+	lastCheckedAt time.Time
+}
+
+func newCertReloader(certFile string, keyFile string, interval time.Duration) (*certReloader, error) {
+	if interval <= 0 {
+		interval = defaultCertReloadInterval
+	}
+
+	reloader := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+	}
+	modTime, err := reloader.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	err = reloader.load(modTime)
+	if err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+func (r *certReloader) latestModTime() (time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if keyInfo.ModTime().After(certInfo.ModTime()) {
+		return keyInfo.ModTime(), nil
+	}
+	return certInfo.ModTime(), nil
+}
+
+func (r *certReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert, r.modTime, r.lastCheckedAt = &cert, modTime, time.Now()
+	return nil
+}
+
+// GetClientCertificate returns the current certificate, the files are checked at most once every interval
+// and the previous certificate is kept when the modified files can't be loaded
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	cert, modTime, isDue := r.cert, r.modTime, time.Since(r.lastCheckedAt) >= r.interval
+	r.mu.RUnlock()
+	if !isDue {
+		return cert, nil
+	}
+
+	latestModTime, err := r.latestModTime()
+	if err == nil && latestModTime.After(modTime) {
+		err = r.load(latestModTime)
+	}
+	if err != nil {
+		log.Printf("[HTTPClient] Error when reloading the client certificate %s: %v", r.certFile, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastCheckedAt = time.Now()
+	return r.cert, nil
+}
+
+// tlsConfig builds the tls config of the transport config
+func (config *TransportConfig) tlsConfig() (*tls.Config, error) {
+	minVersion := config.MinTLSVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+	tlsConfig := &tls.Config{MinVersion: minVersion}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		reloader, err := newCertReloader(config.CertFile, config.KeyFile, config.CertReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = reloader.GetClientCertificate
+	} else if len(config.CertPEM) > 0 {
+		cert, err := tls.X509KeyPair(config.CertPEM, config.KeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(config.RootCAFiles) > 0 || len(config.RootCAPEM) > 0 {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+
+		pems := [][]byte{}
+		for _, file := range config.RootCAFiles {
+			pem, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			pems = append(pems, pem)
+		}
+		if len(config.RootCAPEM) > 0 {
+			pems = append(pems, config.RootCAPEM)
+		}
+
+		for _, pem := range pems {
+			if !rootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("invalid root CA certificate")
+			}
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	return tlsConfig, nil
+}
+
+// NewTransport creates the http transport of the transport config
+func NewTransport(config *TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if config.DialTimeout > 0 || config.KeepAlive > 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if config.DialTimeout > 0 {
+			dialer.Timeout = config.DialTimeout
+		}
+		if config.KeepAlive > 0 {
+			dialer.KeepAlive = config.KeepAlive
+		}
+		transport.DialContext = dialer.DialContext
+	}
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+	if config.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	}
+	if config.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+	}
+
+	return transport, nil
+}
+
+// NewTransportClient creates the http client of the transport config
+func NewTransportClient(config *TransportConfig) (*http.Client, error) {
+	transport, err := NewTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// errorTransport fails every request with the error of building the transport
+type errorTransport struct {
+	err error
+}
+
+func (t *errorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, t.err
+}
@@ -0,0 +1,407 @@
+package data
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/payfazz/commerce-kit/appcontext"
+)
+
+const defaultRowCacheTTL = 5 * time.Minute
+
+// RowCache stores the encoded rows by its key
+type RowCache interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(keys ...string) error
+}
+
+// RedisRowCache is the redis implementation of RowCache
+type RedisRowCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// Get gets the row of the key
+func (c *RedisRowCache) Get(key string) ([]byte, bool, error) {
+	value, err := c.client.Get(c.prefix + key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set sets the row of the key
+func (c *RedisRowCache) Set(key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(c.prefix+key, value, ttl).Err()
+}
+
+// Delete deletes the rows of the keys
+func (c *RedisRowCache) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixedKeys := []string{}
+	for _, key := range keys {
+		prefixedKeys = append(prefixedKeys, c.prefix+key)
+	}
+	return c.client.Del(prefixedKeys...).Err()
+}
+
+// NewRedisRowCache creates a row cache stored in redis
+func NewRedisRowCache(client *redis.Client) *RedisRowCache {
+	return &RedisRowCache{
+		client: client,
+		prefix: "rowcache:",
+	}
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiredAt time.Time
+}
+
+// LRURowCache is the in-process least recently used implementation of RowCache
+type LRURowCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// Get gets the row of the key
+func (c *LRURowCache) Get(key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expiredAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set sets the row of the key
+func (c *LRURowCache) Set(key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = &lruEntry{key: key, value: value, expiredAt: time.Now().Add(ttl)}
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiredAt: time.Now().Add(ttl)})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Delete deletes the rows of the keys
+func (c *LRURowCache) Delete(keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+// NewLRURowCache creates an in-process row cache holding up to the size rows
+func NewLRURowCache(size int) *LRURowCache {
+	return &LRURowCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// cachedRow is the cached row along with the owner scope & the version of the id it was read with
+type cachedRow struct {
+	Owner   *int                       `json:"owner"`
+	Version string                     `json:"version"`
+	Row     map[string]json.RawMessage `json:"row"`
+}
+
+// CachedStorage is the read-through caching decorator of GenericStorage.
+// FindByID is cached by the table, owner & id along with the version of the id read before the storage,
+// the writes by id change the version so the cached rows of every owner, including the rows set
+// by the concurrent reads after the write, aren't used anymore. The cache is bypassed inside a transaction.
+// Only FindByID is cached, Single & the other finds always read the storage, and ExecQuery doesn't invalidate the cache.
+type CachedStorage struct {
+	GenericStorage
+	tableName string
+	cache     RowCache
+	ttl       time.Duration
+}
+
+// key returns the key of the row read by the owner, the nil owner reads the rows of every account
+func (s *CachedStorage) key(owner *int, id interface{}) string {
+	if owner == nil {
+		return fmt.Sprintf("%s:all:%v", s.tableName, id)
+	}
+	return fmt.Sprintf("%s:%d:%v", s.tableName, *owner, id)
+}
+
+// versionKey returns the key of the version of the id shared by the cached rows of every owner
+func (s *CachedStorage) versionKey(id interface{}) string {
+	return fmt.Sprintf("%s:version:%v", s.tableName, id)
+}
+
+// version returns the current version of the id, it's empty until the id is written
+func (s *CachedStorage) version(id interface{}) (string, error) {
+	value, _, err := s.cache.Get(s.versionKey(id))
+	return string(value), err
+}
+
+func sameOwner(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// encodeRow encodes the elem by its db tags so the fields hidden from json are cached too
+func encodeRow(elem interface{}) (map[string]json.RawMessage, error) {
+	v := reflect.Indirect(reflect.ValueOf(elem))
+	row := map[string]json.RawMessage{}
+	for i := 0; i < v.NumField(); i++ {
+		dbTag := v.Type().Field(i).Tag.Get("db")
+		if emptyTag(dbTag) || !v.Field(i).CanInterface() {
+			continue
+		}
+
+		value, err := json.Marshal(v.Field(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		row[dbTag] = value
+	}
+	return row, nil
+}
+
+func decodeRow(row map[string]json.RawMessage, elem interface{}) error {
+	v := reflect.ValueOf(elem).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		value, ok := row[v.Type().Field(i).Tag.Get("db")]
+		if !ok || !v.Field(i).CanSet() {
+			continue
+		}
+
+		err := json.Unmarshal(value, v.Field(i).Addr().Interface())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isCacheable returns false for the models having encrypted fields so the plaintext isn't cached
+func isCacheable(elem interface{}) bool {
+	elemType := reflect.TypeOf(elem)
+	if elemType.Kind() != reflect.Ptr || elemType.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	return len(encryptedFields(elemType.Elem())) == 0
+}
+
+// FindByID finds an element by its id from the cache or the storage
+func (s *CachedStorage) FindByID(ctx *context.Context, elem interface{}, id interface{}) error {
+	if _, ok := TxFromContext(ctx); ok || !isCacheable(elem) {
+		return s.GenericStorage.FindByID(ctx, elem, id)
+	}
+
+	currentAccount := appcontext.CurrentAccount(ctx)
+	key := s.key(currentAccount, id)
+
+	// the version is read before the storage so the row read before a concurrent write is stale once it's set
+	version, err := s.version(id)
+	if err != nil {
+		log.Printf("[CachedStorage] Error when getting %s: %v", s.versionKey(id), err)
+		return s.GenericStorage.FindByID(ctx, elem, id)
+	}
+
+	value, ok, err := s.cache.Get(key)
+	if err != nil {
+		log.Printf("[CachedStorage] Error when getting %s: %v", key, err)
+	}
+	if ok {
+		cached := &cachedRow{}
+		err = json.Unmarshal(value, cached)
+		if err == nil && sameOwner(cached.Owner, currentAccount) && cached.Version == version && decodeRow(cached.Row, elem) == nil {
+			return nil
+		}
+	}
+
+	err = s.GenericStorage.FindByID(ctx, elem, id)
+	if err != nil {
+		return err
+	}
+
+	row, err := encodeRow(elem)
+	if err == nil {
+		value, err = json.Marshal(&cachedRow{Owner: currentAccount, Version: version, Row: row})
+	}
+	if err == nil {
+		err = s.cache.Set(key, value, s.ttl)
+	}
+	if err != nil {
+		log.Printf("[CachedStorage] Error when setting %s: %v", key, err)
+	}
+
+	return nil
+}
+
+// invalidate changes the version of the ids so the cached rows of every owner aren't used anymore,
+// inside a transaction it's changed again after the commit to drop the rows read in the meantime.
+// The version outlives the cached rows set with the previous version.
+func (s *CachedStorage) invalidate(ctx *context.Context, ids []interface{}) {
+	changeVersions := func() {
+		for _, id := range ids {
+			version := strconv.FormatInt(time.Now().UnixNano(), 10)
+			err := s.cache.Set(s.versionKey(id), []byte(version), 2*s.ttl)
+			if err != nil {
+				log.Printf("[CachedStorage] Error when invalidating %s: %v", s.versionKey(id), err)
+			}
+		}
+	}
+
+	changeVersions()
+	AfterCommit(ctx, changeVersions)
+}
+
+// elemIDs returns the ids of the element or the slice of elements
+func elemIDs(elems interface{}) []interface{} {
+	v := reflect.Indirect(reflect.ValueOf(elems))
+	if v.Kind() != reflect.Slice {
+		v = reflect.ValueOf([]interface{}{elems})
+	}
+
+	ids := []interface{}{}
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		for elem.Kind() == reflect.Interface || elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			ids = append(ids, elem.Interface())
+			continue
+		}
+		for j := 0; j < elem.NumField(); j++ {
+			if idTag(elem.Type().Field(j).Tag.Get("db")) {
+				ids = append(ids, elem.Field(j).Interface())
+			}
+		}
+	}
+	return ids
+}
+
+// Update updates the element and invalidates its cached row
+func (s *CachedStorage) Update(ctx *context.Context, elem interface{}) error {
+	err := s.GenericStorage.Update(ctx, elem)
+	s.invalidate(ctx, elemIDs(elem))
+	return err
+}
+
+// UpdateFields updates the columns of the element and invalidates its cached row
+func (s *CachedStorage) UpdateFields(ctx *context.Context, id interface{}, fields map[string]interface{}) error {
+	storage, ok := s.GenericStorage.(PatchStorage)
+	if !ok {
+		return ErrPatchNotSupported
+	}
+
+	err := storage.UpdateFields(ctx, id, fields)
+	s.invalidate(ctx, []interface{}{id})
+	return err
+}
+
+// UpdateWithMask updates the masked columns of the element and invalidates its cached row
+func (s *CachedStorage) UpdateWithMask(ctx *context.Context, elem interface{}, fields []string) error {
+	storage, ok := s.GenericStorage.(PatchStorage)
+	if !ok {
+		return ErrPatchNotSupported
+	}
+
+	err := storage.UpdateWithMask(ctx, elem, fields)
+	s.invalidate(ctx, elemIDs(elem))
+	return err
+}
+
+// UpdateMany updates the elements and invalidates its cached rows
+func (s *CachedStorage) UpdateMany(ctx *context.Context, elems interface{}) error {
+	err := s.GenericStorage.UpdateMany(ctx, elems)
+	s.invalidate(ctx, elemIDs(elems))
+	return err
+}
+
+// UpdateManyWithResult updates the elements and invalidates its cached rows
+func (s *CachedStorage) UpdateManyWithResult(ctx *context.Context, elems interface{}, result interface{}) error {
+	err := s.GenericStorage.UpdateManyWithResult(ctx, elems, result)
+	s.invalidate(ctx, elemIDs(elems))
+	return err
+}
+
+// Delete deletes the element and invalidates its cached row
+func (s *CachedStorage) Delete(ctx *context.Context, id interface{}) error {
+	err := s.GenericStorage.Delete(ctx, id)
+	s.invalidate(ctx, []interface{}{id})
+	return err
+}
+
+// DeleteMany deletes the elements and invalidates its cached rows
+func (s *CachedStorage) DeleteMany(ctx *context.Context, ids interface{}) error {
+	err := s.GenericStorage.DeleteMany(ctx, ids)
+	s.invalidate(ctx, elemIDs(ids))
+	return err
+}
+
+// HardDelete deletes the element and invalidates its cached row
+func (s *CachedStorage) HardDelete(ctx *context.Context, id interface{}) error {
+	err := s.GenericStorage.HardDelete(ctx, id)
+	s.invalidate(ctx, []interface{}{id})
+	return err
+}
+
+// NewCachedStorage creates the caching decorator of the storage of the table
+func NewCachedStorage(storage GenericStorage, tableName string, cache RowCache, ttl time.Duration) *CachedStorage {
+	if ttl <= 0 {
+		ttl = defaultRowCacheTTL
+	}
+
+	return &CachedStorage{
+		GenericStorage: storage,
+		tableName:      tableName,
+		cache:          cache,
+		ttl:            ttl,
+	}
+}
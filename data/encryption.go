@@ -0,0 +1,469 @@
+package data
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrKeyProviderNotSet declare specific error for encrypted fields without key provider
+// ErrKeyNotFound declare specific error for unknown encryption key
+var (
+	ErrKeyProviderNotSet = fmt.Errorf("key provider is not set for the encrypted fields")
+	ErrKeyNotFound       = fmt.Errorf("encryption key is not found")
+)
+
+const (
+	encryptedValuePrefix     = "enc:v1:"
+	defaultRotationBatchSize = 1000
+)
+
+// KeyProvider provides the keys to encrypt the fields tagged `kit:"encrypted"`
+type KeyProvider interface {
+	// CurrentKey returns the id & the key used to encrypt new values
+	CurrentKey() (string, []byte, error)
+	// Key returns the key by its id to decrypt the existing values
+	Key(keyID string) ([]byte, error)
+	// BlindIndexKey returns the key used to compute the blind index
+	BlindIndexKey() ([]byte, error)
+}
+
+// StaticKeyProvider is the in-memory implementation of KeyProvider.
+// To rotate the key, add the new key into Keys and point CurrentKeyID to it,
+// the old keys should be kept until RotateEncryptionKey has re-encrypted all values.
+type StaticKeyProvider struct {
+	CurrentKeyID string
+	Keys         map[string][]byte
+	IndexKey     []byte
+}
+
+// CurrentKey returns the current key
+func (p *StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	key, err := p.Key(p.CurrentKeyID)
+	if err != nil {
+		return "", nil, err
+	}
+	return p.CurrentKeyID, key, nil
+}
+
+// Key returns the key by its id
+func (p *StaticKeyProvider) Key(keyID string) ([]byte, error) {
+	key, ok := p.Keys[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// BlindIndexKey returns the blind index key
+func (p *StaticKeyProvider) BlindIndexKey() ([]byte, error) {
+	if len(p.IndexKey) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return p.IndexKey, nil
+}
+
+// encryptedField represents a field tagged `kit:"encrypted"`
+// with the optional blind index column `kit:"encrypted,blindindex=<column>"`
+type encryptedField struct {
+	dbTag      string
+	blindIndex string
+}
+
+func encryptedFields(elemType reflect.Type) []encryptedField {
+	fields := []encryptedField{}
+	for i := 0; i < elemType.NumField(); i++ {
+		field := elemType.Field(i)
+		dbTag := field.Tag.Get("db")
+		if !kitTag(field, "encrypted") || readOnlyTag(dbTag) || emptyTag(dbTag) {
+			continue
+		}
+		fields = append(fields, encryptedField{
+			dbTag:      dbTag,
+			blindIndex: kitTagValue(field, "blindindex"),
+		})
+	}
+	return fields
+}
+
+// blindIndexColumn returns the blind index column of the db field if exists
+func blindIndexColumn(field reflect.StructField) string {
+	dbTag := field.Tag.Get("db")
+	if !kitTag(field, "encrypted") || readOnlyTag(dbTag) || emptyTag(dbTag) {
+		return ""
+	}
+	return kitTagValue(field, "blindindex")
+}
+
+func (r *PostgresStorage) encryptedField(dbTag string) (encryptedField, bool) {
+	for _, field := range r.encryptedFields {
+		if field.dbTag == dbTag {
+			return field, true
+		}
+	}
+	return encryptedField{}, false
+}
+
+func (r *PostgresStorage) additionalData(dbTag string) []byte {
+	return []byte(fmt.Sprintf("%s.%s", r.tableName, dbTag))
+}
+
+func (r *PostgresStorage) encryptValue(dbTag string, plaintext string) (string, error) {
+	if r.keyProvider == nil {
+		return "", ErrKeyProviderNotSet
+	}
+
+	keyID, key, err := r.keyProvider.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), r.additionalData(dbTag))
+	return fmt.Sprintf("%s%s:%s", encryptedValuePrefix, keyID, base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// decryptValue decrypts the value, the value which is not encrypted is returned as it is
+func (r *PostgresStorage) decryptValue(dbTag string, value string) (string, error) {
+	keyID, sealed, ok := parseEncryptedValue(value)
+	if !ok {
+		return value, nil
+	}
+
+	if r.keyProvider == nil {
+		return "", ErrKeyProviderNotSet
+	}
+
+	key, err := r.keyProvider.Key(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted value of %s is malformed", dbTag)
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], r.additionalData(dbTag))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func parseEncryptedValue(value string) (string, []byte, bool) {
+	if !strings.HasPrefix(value, encryptedValuePrefix) {
+		return "", nil, false
+	}
+
+	chunk := strings.SplitN(strings.TrimPrefix(value, encryptedValuePrefix), ":", 2)
+	if len(chunk) != 2 {
+		return "", nil, false
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(chunk[1])
+	if err != nil {
+		return "", nil, false
+	}
+
+	return chunk[0], sealed, true
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// BlindIndex computes the deterministic blind index of the value for the encrypted db field,
+// it's used to look up the encrypted field by equality, e.g. `"phoneIndex" = :phone`
+func (r *PostgresStorage) BlindIndex(dbTag string, value string) (string, error) {
+	if r.keyProvider == nil {
+		return "", ErrKeyProviderNotSet
+	}
+
+	key, err := r.keyProvider.BlindIndexKey()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(r.additionalData(dbTag))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// encryptArgs encrypts the encrypted fields in the args and fills its blind index
+func (r *PostgresStorage) encryptArgs(args map[string]interface{}) error {
+	for _, field := range r.encryptedFields {
+		value, ok := args[field.dbTag]
+		if !ok {
+			continue
+		}
+
+		plaintext, isNil, err := encryptedFieldString(field.dbTag, value)
+		if err != nil {
+			return err
+		}
+
+		if isNil {
+			if field.blindIndex != "" {
+				args[field.blindIndex] = nil
+			}
+			continue
+		}
+
+		encrypted, err := r.encryptValue(field.dbTag, plaintext)
+		if err != nil {
+			return err
+		}
+		args[field.dbTag] = encrypted
+
+		if field.blindIndex != "" {
+			index, err := r.BlindIndex(field.dbTag, plaintext)
+			if err != nil {
+				return err
+			}
+			args[field.blindIndex] = index
+		}
+	}
+
+	return nil
+}
+
+func encryptedFieldString(dbTag string, value interface{}) (string, bool, error) {
+	switch v := value.(type) {
+	case string:
+		return v, false, nil
+	case *string:
+		if v == nil {
+			return "", true, nil
+		}
+		return *v, false, nil
+	case nil:
+		return "", true, nil
+	default:
+		return "", false, fmt.Errorf("encrypted field %s should be a string", dbTag)
+	}
+}
+
+// decryptResult decrypts the encrypted fields of the scanned element(s) of the storage type
+func (r *PostgresStorage) decryptResult(dest interface{}) error {
+	if len(r.encryptedFields) == 0 || dest == nil {
+		return nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(dest))
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			err := r.decryptElem(reflect.Indirect(v.Index(i)))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return r.decryptElem(v)
+}
+
+func (r *PostgresStorage) decryptElem(v reflect.Value) error {
+	if !v.IsValid() || v.Type() != r.elemType {
+		return nil
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		dbTag := r.elemType.Field(i).Tag.Get("db")
+		if _, ok := r.encryptedField(dbTag); !ok {
+			continue
+		}
+
+		field := v.Field(i)
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		if field.Kind() != reflect.String {
+			continue
+		}
+
+		plaintext, err := r.decryptValue(dbTag, field.String())
+		if err != nil {
+			return err
+		}
+		field.SetString(plaintext)
+	}
+
+	return nil
+}
+
+// RotateEncryptionKey re-encrypts the encrypted fields which are not encrypted with the current key,
+// including the plaintext values stored before the field was encrypted.
+// It returns the number of the rows updated.
+func (r *PostgresStorage) RotateEncryptionKey(ctx *context.Context, batchSize int) (int, error) {
+	if len(r.encryptedFields) == 0 {
+		return 0, nil
+	}
+	if r.keyProvider == nil {
+		return 0, ErrKeyProviderNotSet
+	}
+
+	currentKeyID, _, err := r.keyProvider.CurrentKey()
+	if err != nil {
+		return 0, err
+	}
+
+	if batchSize <= 0 {
+		batchSize = defaultRotationBatchSize
+	}
+
+	db := r.db
+	tx, ok := TxFromContext(ctx)
+	if ok {
+		db = tx
+	}
+
+	columns := []string{`"id"`}
+	for _, field := range r.encryptedFields {
+		columns = append(columns, fmt.Sprintf(`"%s"`, field.dbTag))
+	}
+
+	selectStatement, err := db.PrepareNamed(fmt.Sprintf(`
+		SELECT %s FROM "%s" WHERE "id" > :lastId ORDER BY "id" LIMIT :limit`,
+		strings.Join(columns, ","), r.tableName))
+	if err != nil {
+		return 0, err
+	}
+	defer selectStatement.Close()
+
+	updated := 0
+	lastID := int64(0)
+	for {
+		rows, err := selectStatement.Queryx(map[string]interface{}{
+			"lastId": lastID,
+			"limit":  batchSize,
+		})
+		if err != nil {
+			return updated, err
+		}
+
+		records := []map[string]interface{}{}
+		for rows.Next() {
+			record := map[string]interface{}{}
+			err = rows.MapScan(record)
+			if err != nil {
+				rows.Close()
+				return updated, err
+			}
+			records = append(records, record)
+		}
+		rows.Close()
+
+		for _, record := range records {
+			id, ok := record["id"].(int64)
+			if !ok {
+				return updated, fmt.Errorf("id of %s should be an integer", r.tableName)
+			}
+			lastID = id
+
+			isUpdated, err := r.reencryptRecord(db, currentKeyID, record)
+			if err != nil {
+				return updated, err
+			}
+			if isUpdated {
+				updated++
+			}
+		}
+
+		if len(records) < batchSize {
+			return updated, nil
+		}
+	}
+}
+
+func (r *PostgresStorage) reencryptRecord(db Queryer, currentKeyID string, record map[string]interface{}) (bool, error) {
+	setFields := []string{}
+	args := map[string]interface{}{
+		"id": record["id"],
+	}
+
+	for _, field := range r.encryptedFields {
+		var value string
+		switch v := record[field.dbTag].(type) {
+		case []byte:
+			value = string(v)
+		case string:
+			value = v
+		default:
+			continue
+		}
+
+		keyID, _, ok := parseEncryptedValue(value)
+		if ok && keyID == currentKeyID {
+			continue
+		}
+
+		plaintext, err := r.decryptValue(field.dbTag, value)
+		if err != nil {
+			return false, err
+		}
+
+		fieldArgs := map[string]interface{}{
+			field.dbTag: plaintext,
+		}
+		err = r.encryptArgs(fieldArgs)
+		if err != nil {
+			return false, err
+		}
+
+		for column, columnValue := range fieldArgs {
+			setFields = append(setFields, fmt.Sprintf(`"%s" = :%s`, column, column))
+			args[column] = columnValue
+		}
+	}
+
+	if len(setFields) == 0 {
+		return false, nil
+	}
+
+	statement, err := db.PrepareNamed(fmt.Sprintf(`UPDATE "%s" SET %s WHERE "id" = :id`,
+		r.tableName, strings.Join(setFields, ",")))
+	if err != nil {
+		return false, err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(args)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
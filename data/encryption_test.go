@@ -0,0 +1,232 @@
+package data
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type encryptedCustomer struct {
+	ID         int     `db:"id"`
+	Phone      string  `db:"phone" kit:"encrypted,blindindex=phoneIndex"`
+	Email      *string `db:"email" kit:"encrypted"`
+	PhoneIndex string  `db:"phoneIndex"`
+}
+
+func newEncryptedStorage(provider KeyProvider) *PostgresStorage {
+	elemType := reflect.TypeOf(encryptedCustomer{})
+	return &PostgresStorage{
+		tableName:       "customer",
+		elemType:        elemType,
+		encryptedFields: encryptedFields(elemType),
+		keyProvider:     provider,
+	}
+}
+
+func newKeyProvider() *StaticKeyProvider {
+	return &StaticKeyProvider{
+		CurrentKeyID: "k1",
+		Keys: map[string][]byte{
+			"k1": []byte("0123456789abcdef0123456789abcdef"),
+			"k2": []byte("fedcba9876543210fedcba9876543210"),
+		},
+		IndexKey: []byte("index-key"),
+	}
+}
+
+func TestEncryptDecryptValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{"empty", ""},
+		{"phone", "+6281234567890"},
+		{"unicode", "Jl. Sudirman № 1 – Jakarta"},
+		{"prefix like", "enc:v1:k1:not-encrypted"},
+	}
+
+	r := newEncryptedStorage(newKeyProvider())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := r.encryptValue("phone", tt.plaintext)
+			if err != nil {
+				t.Fatalf("encryptValue() error = %v", err)
+			}
+			if !strings.HasPrefix(encrypted, "enc:v1:k1:") {
+				t.Fatalf("encryptValue() = %q, want the enc:v1:k1: prefix", encrypted)
+			}
+
+			decrypted, err := r.decryptValue("phone", encrypted)
+			if err != nil {
+				t.Fatalf("decryptValue() error = %v", err)
+			}
+			if decrypted != tt.plaintext {
+				t.Errorf("decryptValue() = %q, want %q", decrypted, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptValueUsesRandomNonce(t *testing.T) {
+	r := newEncryptedStorage(newKeyProvider())
+	first, _ := r.encryptValue("phone", "+6281234567890")
+	second, _ := r.encryptValue("phone", "+6281234567890")
+	if first == second {
+		t.Errorf("encryptValue() returned the same ciphertext twice: %q", first)
+	}
+}
+
+func TestParseEncryptedValue(t *testing.T) {
+	sealed := []byte("sealed-bytes")
+	encoded := base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name       string
+		value      string
+		wantKeyID  string
+		wantSealed []byte
+		wantOK     bool
+	}{
+		{"valid", "enc:v1:k1:" + encoded, "k1", sealed, true},
+		{"key id with dash", "enc:v1:key-2020:" + encoded, "key-2020", sealed, true},
+		{"plaintext", "+6281234567890", "", nil, false},
+		{"other version", "enc:v2:k1:" + encoded, "", nil, false},
+		{"missing key id", "enc:v1:" + encoded, "", nil, false},
+		{"invalid base64", "enc:v1:k1:not base64!", "", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyID, gotSealed, ok := parseEncryptedValue(tt.value)
+			if ok != tt.wantOK || keyID != tt.wantKeyID || !reflect.DeepEqual(gotSealed, tt.wantSealed) {
+				t.Errorf("parseEncryptedValue(%q) = %q, %v, %v, want %q, %v, %v",
+					tt.value, keyID, gotSealed, ok, tt.wantKeyID, tt.wantSealed, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDecryptValueNotEncrypted(t *testing.T) {
+	r := newEncryptedStorage(nil)
+	decrypted, err := r.decryptValue("phone", "+6281234567890")
+	if err != nil || decrypted != "+6281234567890" {
+		t.Errorf("decryptValue() = %q, %v, want the plaintext as it is", decrypted, err)
+	}
+}
+
+func TestDecryptValueAfterRotation(t *testing.T) {
+	provider := newKeyProvider()
+	r := newEncryptedStorage(provider)
+
+	oldValue, err := r.encryptValue("phone", "+6281234567890")
+	if err != nil {
+		t.Fatalf("encryptValue() error = %v", err)
+	}
+
+	provider.CurrentKeyID = "k2"
+	newValue, err := r.encryptValue("phone", "+6281234567890")
+	if err != nil {
+		t.Fatalf("encryptValue() error = %v", err)
+	}
+	if keyID, _, _ := parseEncryptedValue(newValue); keyID != "k2" {
+		t.Errorf("encryptValue() after rotation used key %q, want k2", keyID)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"old key", oldValue},
+		{"new key", newValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decrypted, err := r.decryptValue("phone", tt.value)
+			if err != nil || decrypted != "+6281234567890" {
+				t.Errorf("decryptValue() = %q, %v, want the plaintext", decrypted, err)
+			}
+		})
+	}
+
+	delete(provider.Keys, "k1")
+	if _, err = r.decryptValue("phone", oldValue); err != ErrKeyNotFound {
+		t.Errorf("decryptValue() with the removed key error = %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestDecryptValueRejectsTampering(t *testing.T) {
+	r := newEncryptedStorage(newKeyProvider())
+	encrypted, err := r.encryptValue("phone", "+6281234567890")
+	if err != nil {
+		t.Fatalf("encryptValue() error = %v", err)
+	}
+	keyID, sealed, _ := parseEncryptedValue(encrypted)
+
+	flip := func(i int) string {
+		tampered := append([]byte{}, sealed...)
+		tampered[i] ^= 0x01
+		return encryptedValuePrefix + keyID + ":" + base64.StdEncoding.EncodeToString(tampered)
+	}
+
+	tests := []struct {
+		name  string
+		dbTag string
+		value string
+	}{
+		{"nonce", "phone", flip(0)},
+		{"ciphertext", "phone", flip(len(sealed) / 2)},
+		{"tag", "phone", flip(len(sealed) - 1)},
+		{"truncated", "phone", encryptedValuePrefix + keyID + ":" + base64.StdEncoding.EncodeToString(sealed[:4])},
+		{"other column", "email", encrypted},
+		{"other key", "phone", encryptedValuePrefix + "k2:" + base64.StdEncoding.EncodeToString(sealed)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if decrypted, err := r.decryptValue(tt.dbTag, tt.value); err == nil {
+				t.Errorf("decryptValue() = %q, want an error", decrypted)
+			}
+		})
+	}
+}
+
+func TestEncryptArgsAndDecryptResult(t *testing.T) {
+	r := newEncryptedStorage(newKeyProvider())
+	email := "john@example.com"
+	args := map[string]interface{}{
+		"id":    1,
+		"phone": "+6281234567890",
+		"email": &email,
+	}
+	if err := r.encryptArgs(args); err != nil {
+		t.Fatalf("encryptArgs() error = %v", err)
+	}
+
+	wantIndex, _ := r.BlindIndex("phone", "+6281234567890")
+	if args["phoneIndex"] != wantIndex {
+		t.Errorf("encryptArgs() phoneIndex = %v, want %v", args["phoneIndex"], wantIndex)
+	}
+
+	customer := &encryptedCustomer{ID: 1, Phone: args["phone"].(string)}
+	encryptedEmail := args["email"].(string)
+	customer.Email = &encryptedEmail
+	if err := r.decryptResult(customer); err != nil {
+		t.Fatalf("decryptResult() error = %v", err)
+	}
+	if customer.Phone != "+6281234567890" || *customer.Email != email {
+		t.Errorf("decryptResult() = %q, %q, want the plaintexts", customer.Phone, *customer.Email)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	r := newEncryptedStorage(newKeyProvider())
+	first, _ := r.BlindIndex("phone", "+6281234567890")
+	second, _ := r.BlindIndex("phone", "+6281234567890")
+	other, _ := r.BlindIndex("email", "+6281234567890")
+	if first != second {
+		t.Errorf("BlindIndex() isn't deterministic: %q != %q", first, second)
+	}
+	if first == other {
+		t.Errorf("BlindIndex() of the different columns are equal: %q", first)
+	}
+}
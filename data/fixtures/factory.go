@@ -0,0 +1,191 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/payfazz/commerce-kit/data"
+	"github.com/payfazz/commerce-kit/types"
+)
+
+// factoryBaseTime is the base of the generated time values so the fake data stays deterministic
+var factoryBaseTime = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Generator generates the value of a field for the n-th built model
+type Generator func(n int) interface{}
+
+// Factory builds the models with deterministic fake data and persists them through the storage.
+// Only the zero required fields (tagged `validate:"required"`) are filled unless a generator is defined.
+type Factory struct {
+	storage    data.GenericStorage
+	elemType   reflect.Type
+	generators map[string]Generator
+	mu         sync.Mutex
+	sequence   int
+}
+
+// Define sets the generator of the field by its db tag or field name
+func (f *Factory) Define(field string, generator Generator) *Factory {
+	f.generators[field] = generator
+	return f
+}
+
+func (f *Factory) next() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sequence++
+	return f.sequence
+}
+
+func isRequired(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmail(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == "email" {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeValue returns the deterministic fake value of the field for the n-th model
+func fakeValue(field reflect.StructField, n int) (reflect.Value, bool) {
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Ptr {
+		value, ok := fakeValue(reflect.StructField{Name: field.Name, Type: fieldType.Elem(), Tag: field.Tag}, n)
+		if !ok {
+			return value, false
+		}
+		ptr := reflect.New(fieldType.Elem())
+		ptr.Elem().Set(value)
+		return ptr, true
+	}
+
+	switch fieldType {
+	case reflect.TypeOf(time.Time{}):
+		return reflect.ValueOf(factoryBaseTime.Add(time.Duration(n) * time.Hour)), true
+	case reflect.TypeOf(types.Metadata{}):
+		return reflect.ValueOf(types.Metadata{}), true
+	}
+
+	value := reflect.New(fieldType).Elem()
+	switch fieldType.Kind() {
+	case reflect.String:
+		if isEmail(field) {
+			value.SetString(fmt.Sprintf("%s%d@example.com", strings.ToLower(field.Name), n))
+		} else {
+			value.SetString(fmt.Sprintf("%s %d", field.Name, n))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(float64(n))
+	case reflect.Bool:
+		value.SetBool(true)
+	case reflect.Slice:
+		value.Set(reflect.MakeSlice(fieldType, 0, 0))
+	case reflect.Map:
+		value.Set(reflect.MakeMap(fieldType))
+	default:
+		return value, false
+	}
+	return value, true
+}
+
+// Build fills the zero required fields of the elem pointer with the fake data
+func (f *Factory) Build(elem interface{}) error {
+	v := reflect.ValueOf(elem)
+	if v.Kind() != reflect.Ptr || v.Elem().Type() != f.elemType {
+		return fmt.Errorf("elem should be a pointer of %s", f.elemType)
+	}
+	v = v.Elem()
+
+	n := f.next()
+	for i := 0; i < f.elemType.NumField(); i++ {
+		field := f.elemType.Field(i)
+		dbTag := field.Tag.Get("db")
+		if dbTag == "" || dbTag == "-" || dbTag == "id" || !v.Field(i).CanSet() || !v.Field(i).IsZero() {
+			continue
+		}
+
+		generator, ok := f.generators[dbTag]
+		if !ok {
+			generator, ok = f.generators[field.Name]
+		}
+		if ok {
+			generated := reflect.ValueOf(generator(n))
+			if !generated.IsValid() {
+				// the nil value keeps the zero value of the field
+				continue
+			}
+			if !generated.Type().ConvertibleTo(field.Type) {
+				return fmt.Errorf("generator of %s.%s returns %s, it can't be converted into %s",
+					f.elemType, field.Name, generated.Type(), field.Type)
+			}
+			v.Field(i).Set(generated.Convert(field.Type))
+			continue
+		}
+
+		if !isRequired(field) {
+			continue
+		}
+		if value, ok := fakeValue(field, n); ok {
+			v.Field(i).Set(value)
+		}
+	}
+
+	return nil
+}
+
+// Create builds the elem pointer and inserts it through the storage
+func (f *Factory) Create(ctx *context.Context, elem interface{}) error {
+	err := f.Build(elem)
+	if err != nil {
+		return err
+	}
+
+	return f.storage.Insert(ctx, elem)
+}
+
+// CreateMany creates the number of models and returns them as a slice of pointers of the model
+func (f *Factory) CreateMany(ctx *context.Context, count int) (interface{}, error) {
+	elems := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(f.elemType)), 0, count)
+	for i := 0; i < count; i++ {
+		elem := reflect.New(f.elemType)
+		err := f.Create(ctx, elem.Interface())
+		if err != nil {
+			return nil, err
+		}
+		elems = reflect.Append(elems, elem)
+	}
+
+	return elems.Interface(), nil
+}
+
+// NewFactory creates a new factory of the model persisted through the storage
+func NewFactory(storage data.GenericStorage, elem interface{}) *Factory {
+	elemType := reflect.TypeOf(elem)
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	return &Factory{
+		storage:    storage,
+		elemType:   elemType,
+		generators: map[string]Generator{},
+	}
+}
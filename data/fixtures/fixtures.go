@@ -0,0 +1,322 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// referencePrefix marks a value referencing another fixture, e.g. `$customer_1` for its id
+// or `$customer_1.code` for its column
+const referencePrefix = "$"
+
+// fixture represents a single row to be inserted
+type fixture struct {
+	name    string
+	table   string
+	columns map[string]interface{}
+}
+
+// Loader loads the fixture files into the tables.
+// The fixture files are grouped by the table name then the fixture name:
+//
+//	customer:
+//	  customer_1:
+//	    name: John
+//	order:
+//	  order_1:
+//	    customerId: $customer_1
+type Loader struct {
+	db       *sqlx.DB
+	fixtures map[string]*fixture
+	rows     map[string]map[string]interface{}
+}
+
+// normalize converts the yaml maps into the json compatible maps
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, val := range v {
+			m[fmt.Sprintf("%v", key)] = normalize(val)
+		}
+		return m
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = normalize(val)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = normalize(val)
+		}
+		return v
+	}
+	return value
+}
+
+// Add adds the fixtures grouped by table & fixture name
+func (l *Loader) Add(tables map[string]map[string]map[string]interface{}) error {
+	for table, fixtures := range tables {
+		for name, columns := range fixtures {
+			if _, ok := l.fixtures[name]; ok {
+				return fmt.Errorf("duplicate fixture %s", name)
+			}
+			l.fixtures[name] = &fixture{
+				name:    name,
+				table:   table,
+				columns: normalize(columns).(map[string]interface{}),
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFiles reads the YAML (.yml, .yaml) or JSON (.json) fixture files
+func (l *Loader) LoadFiles(paths ...string) error {
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		tables := map[string]map[string]map[string]interface{}{}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yml", ".yaml":
+			err = yaml.Unmarshal(content, &tables)
+		case ".json":
+			err = json.Unmarshal(content, &tables)
+		default:
+			err = fmt.Errorf("unsupported fixture file")
+		}
+		if err != nil {
+			return fmt.Errorf("error when reading %s: %v", path, err)
+		}
+
+		err = l.Add(tables)
+		if err != nil {
+			return fmt.Errorf("error when reading %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// reference returns the referenced fixture & column of the value
+func reference(value interface{}) (string, string, bool) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, referencePrefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(s, referencePrefix), ".", 2)
+	column := "id"
+	if len(parts) == 2 {
+		column = parts[1]
+	}
+	return parts[0], column, true
+}
+
+// sortFixtures orders the fixtures so the referenced fixtures are inserted first
+func (l *Loader) sortFixtures() ([]*fixture, error) {
+	names := []string{}
+	for name := range l.fixtures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sorted := []*fixture{}
+	visited := map[string]bool{}
+	visiting := map[string]bool{}
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("circular reference on fixture %s", name)
+		}
+		f, ok := l.fixtures[name]
+		if !ok {
+			return fmt.Errorf("fixture %s is not found", name)
+		}
+
+		visiting[name] = true
+		columns := []string{}
+		for column := range f.columns {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		for _, column := range columns {
+			if ref, _, ok := reference(f.columns[column]); ok {
+				err := visit(ref)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		visiting[name] = false
+		visited[name] = true
+
+		sorted = append(sorted, f)
+		return nil
+	}
+
+	for _, name := range names {
+		err := visit(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+// Load inserts the fixtures in the dependency order and resets the id sequences of the tables
+func (l *Loader) Load() error {
+	fixtures, err := l.sortFixtures()
+	if err != nil {
+		return err
+	}
+
+	tx, err := l.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("error when creating transction: %v", err)
+	}
+
+	tables := map[string]bool{}
+	for _, f := range fixtures {
+		row, err := l.insert(tx, f)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error when inserting fixture %s: %v", f.name, err)
+		}
+		l.rows[f.name] = row
+		if _, ok := row["id"]; ok {
+			tables[f.table] = true
+		}
+	}
+
+	for table := range tables {
+		_, err = tx.Exec(fmt.Sprintf(`
+			SELECT setval(pg_get_serial_sequence('"%s"', 'id'), COALESCE(MAX("id"), 0) + 1, false)
+			FROM "%s"`, table, table))
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error when resetting the sequence of %s: %v", table, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error when committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (l *Loader) insert(tx *sqlx.Tx, f *fixture) (map[string]interface{}, error) {
+	columns := []string{}
+	for column := range f.columns {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	fields := []string{}
+	params := []string{}
+	args := []interface{}{}
+	for i, column := range columns {
+		value := f.columns[column]
+		if ref, refColumn, ok := reference(value); ok {
+			refValue, ok := l.rows[ref][refColumn]
+			if !ok {
+				return nil, fmt.Errorf("column %s of fixture %s is not found", refColumn, ref)
+			}
+			value = refValue
+		}
+
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			valueBytes, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			value = string(valueBytes)
+		}
+
+		fields = append(fields, fmt.Sprintf(`"%s"`, column))
+		params = append(params, fmt.Sprintf("$%d", i+1))
+		args = append(args, value)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO "%s" DEFAULT VALUES RETURNING *`, f.table)
+	if len(fields) > 0 {
+		query = fmt.Sprintf(`INSERT INTO "%s"(%s) VALUES (%s) RETURNING *`,
+			f.table, strings.Join(fields, ","), strings.Join(params, ","))
+	}
+
+	rows, err := tx.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	row := map[string]interface{}{}
+	if rows.Next() {
+		err = rows.MapScan(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for column, value := range row {
+		if b, ok := value.([]byte); ok {
+			row[column] = string(b)
+		}
+	}
+
+	return row, rows.Err()
+}
+
+// Row returns the inserted row of the fixture
+func (l *Loader) Row(name string) map[string]interface{} {
+	return l.rows[name]
+}
+
+// ID returns the inserted id of the fixture
+func (l *Loader) ID(name string) int {
+	id, _ := l.rows[name]["id"].(int64)
+	return int(id)
+}
+
+// NewLoader creates a new fixtures loader
+func NewLoader(db *sqlx.DB) *Loader {
+	return &Loader{
+		db:       db,
+		fixtures: map[string]*fixture{},
+		rows:     map[string]map[string]interface{}{},
+	}
+}
+
+// TestLoad loads the fixture files into the db
+func TestLoad(t *testing.T, db *sqlx.DB, paths ...string) *Loader {
+	loader := NewLoader(db)
+
+	err := loader.LoadFiles(paths...)
+	if err != nil {
+		t.Fatalf("failed to read fixtures: %v", err)
+	}
+
+	err = loader.Load()
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+
+	return loader
+}
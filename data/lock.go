@@ -0,0 +1,154 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrLockNotAcquired is returned when the lock is being held by another process
+var ErrLockNotAcquired = fmt.Errorf("lock is not acquired")
+
+// lockID hashes the key into the advisory lock id
+func lockID(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// withXactLock runs the f inside the transaction after the transaction-level lock of the key is taken,
+// the lock is released when the transaction is committed or rolled back.
+// It joins the running transaction of the context if exists.
+func (m *Manager) withXactLock(ctx *context.Context, key string, wait bool, f func(tctx *context.Context) error) error {
+	lock := func(tctx *context.Context) error {
+		tx, _ := TxFromContext(tctx)
+		if wait {
+			return tx.Get(new(string), tx.Rebind(`SELECT cast(pg_advisory_xact_lock(?) as text)`), lockID(key))
+		}
+
+		var acquired bool
+		err := tx.Get(&acquired, tx.Rebind(`SELECT pg_try_advisory_xact_lock(?)`), lockID(key))
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			return ErrLockNotAcquired
+		}
+		return nil
+	}
+
+	if _, ok := TxFromContext(ctx); ok {
+		err := lock(ctx)
+		if err != nil {
+			return err
+		}
+		return f(ctx)
+	}
+
+	return m.RunInTransaction(ctx, func(tctx *context.Context) error {
+		err := lock(tctx)
+		if err != nil {
+			return err
+		}
+		return f(tctx)
+	})
+}
+
+// WithLock runs the f in a transaction holding the lock of the key,
+// it waits until the lock is released by the other holder
+func (m *Manager) WithLock(ctx *context.Context, key string, f func(tctx *context.Context) error) error {
+	return m.withXactLock(ctx, key, true, f)
+}
+
+// TryWithLock runs the f in a transaction holding the lock of the key,
+// it returns ErrLockNotAcquired without running the f when the lock is held by the other holder
+func (m *Manager) TryWithLock(ctx *context.Context, key string, f func(tctx *context.Context) error) error {
+	return m.withXactLock(ctx, key, false, f)
+}
+
+// Lock is a session-level lock held on a dedicated connection until it's released or its lease expires
+type Lock struct {
+	conn  *sqlx.Conn
+	id    int64
+	mu    sync.Mutex
+	timer *time.Timer
+	done  chan struct{}
+	once  sync.Once
+}
+
+// Done is closed when the lock is released or its lease expires
+func (l *Lock) Done() <-chan struct{} {
+	return l.done
+}
+
+// Extend extends the lease of the lock from now, it returns false when the lock has been released
+func (l *Lock) Extend(lease time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return false
+	default:
+	}
+	l.timer.Reset(lease)
+	return true
+}
+
+// Release releases the lock and its connection
+func (l *Lock) Release() error {
+	var err error
+	l.once.Do(func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.timer.Stop()
+		close(l.done)
+
+		_, err = l.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, l.id)
+		closeErr := l.conn.Close()
+		if err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+// AcquireLock takes the session-level lock of the key for long running jobs without waiting,
+// the lock is released automatically when the lease expires unless it's extended.
+// It returns ErrLockNotAcquired when the lock is held by the other holder.
+func (m *Manager) AcquireLock(ctx *context.Context, key string, lease time.Duration) (*Lock, error) {
+	conn, err := m.db.Connx(*ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id := lockID(key)
+	var acquired bool
+	err = conn.GetContext(*ctx, &acquired, `SELECT pg_try_advisory_lock($1)`, id)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, ErrLockNotAcquired
+	}
+
+	lock := &Lock{
+		conn: conn,
+		id:   id,
+		done: make(chan struct{}),
+	}
+	lock.mu.Lock()
+	lock.timer = time.AfterFunc(lease, func() {
+		lock.Release()
+	})
+	lock.mu.Unlock()
+
+	return lock, nil
+}
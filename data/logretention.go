@@ -0,0 +1,342 @@
+package data
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPartitionAhead = 3
+	defaultArchivePrefix  = "archive/activitylog"
+)
+
+// LogArchiver stores the archived activity log partitions, it's implemented by uploader.Service
+type LogArchiver interface {
+	Archive(ctx *context.Context, key string, reader io.Reader) error
+}
+
+// LogRetentionConfig represents the configuration for the activity log partitions & retention.
+// RetentionMonths 0 means the partitions are kept forever.
+type LogRetentionConfig struct {
+	PartitionAhead  int
+	RetentionMonths int
+	DetachOnly      bool
+	Archiver        LogArchiver
+	ArchivePrefix   string
+}
+
+// logPartition represents a monthly partition of the log table
+type logPartition struct {
+	name  string
+	start time.Time
+}
+
+// PartitionedLogTableQuery returns the query to create the log table partitioned monthly by "createdAt",
+// it's meant to be used in the migration
+func PartitionedLogTableQuery(logName string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (
+		"id" BIGSERIAL NOT NULL,
+		"owner" INT,
+		"userId" INT,
+		"userType" VARCHAR(255),
+		"tableName" VARCHAR(255),
+		"referenceId" INT,
+		"metadata" JSONB,
+		"valueBefore" JSONB,
+		"valueAfter" JSONB,
+		"transactionTime" TIMESTAMP,
+		"transactionType" VARCHAR(255),
+		"createdAt" TIMESTAMP NOT NULL,
+		"createdBy" INT,
+		PRIMARY KEY ("id", "createdAt")
+	) PARTITION BY RANGE ("createdAt")`, logName)
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (r *LogStorage) partitionName(start time.Time) string {
+	return fmt.Sprintf("%s_y%04dm%02d", r.logName, start.Year(), int(start.Month()))
+}
+
+func (r *LogStorage) defaultPartitionName() string {
+	return fmt.Sprintf("%s_default", r.logName)
+}
+
+// CreatePartitions creates the DEFAULT partition and the monthly partitions of the log table
+// from the current month until the number of months ahead.
+// The DEFAULT partition keeps the logs written when the job misses its runs,
+// its rows are moved into the monthly partition when the partition is created.
+func (r *LogStorage) CreatePartitions(ctx *context.Context, monthsAhead int) error {
+	db := r.db
+	tx, ok := TxFromContext(ctx)
+	if ok {
+		db = tx
+	}
+
+	err := r.execNamed(db, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" PARTITION OF "%s" DEFAULT`,
+		r.defaultPartitionName(), r.logName), map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+
+	partitions, err := r.partitions(ctx)
+	if err != nil {
+		return err
+	}
+	isAttached := map[string]bool{}
+	for _, partition := range partitions {
+		isAttached[partition.name] = true
+	}
+
+	current := monthStart(time.Now())
+	for i := 0; i <= monthsAhead; i++ {
+		start := current.AddDate(0, i, 0)
+		name := r.partitionName(start)
+		if isAttached[name] {
+			continue
+		}
+
+		err = r.attachPartition(ctx, name, start, start.AddDate(0, 1, 0))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachPartition creates the monthly partition with the rows of its range moved from the DEFAULT partition,
+// the partition can't be created by PARTITION OF while the DEFAULT partition has the rows of its range.
+// It's run in a transaction locking the DEFAULT partition so no log lands in the range before the partition is attached.
+func (r *LogStorage) attachPartition(ctx *context.Context, name string, start time.Time, end time.Time) error {
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+	queries := []string{
+		fmt.Sprintf(`LOCK TABLE "%s" IN ACCESS EXCLUSIVE MODE`, r.defaultPartitionName()),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (LIKE "%s" INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`,
+			name, r.logName),
+		fmt.Sprintf(`WITH "moved" AS (
+				DELETE FROM "%s" WHERE "createdAt" >= '%s' AND "createdAt" < '%s' RETURNING *
+			)
+			INSERT INTO "%s" SELECT * FROM "moved"`,
+			r.defaultPartitionName(), from, to, name),
+		fmt.Sprintf(`ALTER TABLE "%s" ATTACH PARTITION "%s" FOR VALUES FROM ('%s') TO ('%s')`,
+			r.logName, name, from, to),
+	}
+
+	return runInTx(ctx, r.db, func(tctx *context.Context, tx Queryer) error {
+		for _, query := range queries {
+			err := r.execNamed(tx, query, map[string]interface{}{})
+			if err != nil {
+				return fmt.Errorf("error when creating the partition %s: %v", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// partitions lists the attached partitions of the log table ordered by its month
+func (r *LogStorage) partitions(ctx *context.Context) ([]*logPartition, error) {
+	db := r.db
+	tx, ok := TxFromContext(ctx)
+	if ok {
+		db = tx
+	}
+
+	names := []string{}
+	err := db.Select(&names, db.Rebind(`
+		SELECT "child"."relname"
+		FROM "pg_inherits"
+		JOIN "pg_class" "parent" ON "pg_inherits"."inhparent" = "parent"."oid"
+		JOIN "pg_class" "child" ON "pg_inherits"."inhrelid" = "child"."oid"
+		WHERE "parent"."relname" = ?`), r.logName)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := []*logPartition{}
+	for _, name := range names {
+		var year, month int
+		_, err := fmt.Sscanf(strings.TrimPrefix(name, r.logName+"_"), "y%04dm%02d", &year, &month)
+		if err != nil {
+			// not a partition managed by the log storage
+			continue
+		}
+		partitions = append(partitions, &logPartition{
+			name:  name,
+			start: time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC),
+		})
+	}
+
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].start.Before(partitions[j].start)
+	})
+
+	return partitions, nil
+}
+
+// exportPartition writes the rows of the partition as gzipped JSON lines into the archiver
+func (r *LogStorage) exportPartition(ctx *context.Context, partition *logPartition, cfg LogRetentionConfig) error {
+	statement, err := r.db.PrepareNamed(fmt.Sprintf(`SELECT row_to_json("p") FROM "%s" "p" ORDER BY "id"`, partition.name))
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Queryx(map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		gzipWriter := gzip.NewWriter(writer)
+		for rows.Next() {
+			var line []byte
+			err := rows.Scan(&line)
+			if err == nil {
+				_, err = gzipWriter.Write(append(line, '\n'))
+			}
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			writer.CloseWithError(err)
+			return
+		}
+		writer.CloseWithError(gzipWriter.Close())
+	}()
+
+	prefix := cfg.ArchivePrefix
+	if prefix == "" {
+		prefix = defaultArchivePrefix
+	}
+
+	err = cfg.Archiver.Archive(ctx, fmt.Sprintf("%s/%s.jsonl.gz", prefix, partition.name), reader)
+	// unblock the writer when the archiver stops reading early,
+	// the rows are closed only after the writer stops using them
+	reader.CloseWithError(io.ErrClosedPipe)
+	<-done
+	return err
+}
+
+// ApplyRetention archives & detaches (or drops) the partitions older than the retention months
+func (r *LogStorage) ApplyRetention(ctx *context.Context, cfg LogRetentionConfig) error {
+	if cfg.RetentionMonths <= 0 {
+		return nil
+	}
+
+	partitions, err := r.partitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	cutoff := monthStart(time.Now()).AddDate(0, -cfg.RetentionMonths, 0)
+	for _, partition := range partitions {
+		if !partition.start.Before(cutoff) {
+			break
+		}
+
+		if cfg.Archiver != nil {
+			err = r.exportPartition(ctx, partition, cfg)
+			if err != nil {
+				return fmt.Errorf("error when archiving %s: %v", partition.name, err)
+			}
+		}
+
+		err = r.execNamed(r.db, fmt.Sprintf(`ALTER TABLE "%s" DETACH PARTITION "%s"`, r.logName, partition.name), map[string]interface{}{})
+		if err != nil {
+			return err
+		}
+
+		if !cfg.DetachOnly {
+			err = r.execNamed(r.db, fmt.Sprintf(`DROP TABLE IF EXISTS "%s"`, partition.name), map[string]interface{}{})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// LogRetentionJob maintains the partitions & the retention of the log storage,
+// Run can be called by any scheduler or periodically by Start
+type LogRetentionJob struct {
+	logStorage *LogStorage
+	config     LogRetentionConfig
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// Run creates the partitions ahead then applies the retention policy
+func (j *LogRetentionJob) Run(ctx *context.Context) error {
+	err := j.logStorage.CreatePartitions(ctx, j.config.PartitionAhead)
+	if err != nil {
+		return err
+	}
+
+	return j.logStorage.ApplyRetention(ctx, j.config)
+}
+
+// Start runs the job immediately and then every interval until Stop is called
+func (j *LogRetentionJob) Start(interval time.Duration) {
+	j.stop = make(chan struct{})
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			ctx := context.Background()
+			err := j.Run(&ctx)
+			if err != nil {
+				log.Printf("[LogRetentionJob] Error: %v", err)
+			}
+
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic job and waits for the running one to finish
+func (j *LogRetentionJob) Stop() {
+	if j.stop == nil {
+		return
+	}
+	close(j.stop)
+	j.wg.Wait()
+	j.stop = nil
+}
+
+// NewLogRetentionJob creates a new retention job of the log storage
+func NewLogRetentionJob(logStorage *LogStorage, cfg LogRetentionConfig) *LogRetentionJob {
+	if cfg.PartitionAhead <= 0 {
+		cfg.PartitionAhead = defaultPartitionAhead
+	}
+
+	return &LogRetentionJob{
+		logStorage: logStorage,
+		config:     cfg,
+	}
+}
@@ -0,0 +1,365 @@
+package data
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LogWriterMode represents how the activity logs are written into the log table
+type LogWriterMode string
+
+// Enum value for log writer mode
+const (
+	// LogWriterSync writes the activity log inside the caller's transaction
+	LogWriterSync LogWriterMode = "sync"
+	// LogWriterAsync buffers the activity logs and writes them in batches in the background
+	LogWriterAsync LogWriterMode = "async"
+)
+
+// SensitiveMode represents how the fields tagged `kit:"sensitive"` are written into the activity log
+type SensitiveMode string
+
+// Enum value for sensitive mode
+const (
+	// SensitiveMask replaces the value with a fixed mask
+	SensitiveMask SensitiveMode = "mask"
+	// SensitiveHash replaces the value with its HMAC-SHA256 keyed by the HashSalt, the salt is required
+	SensitiveHash SensitiveMode = "hash"
+)
+
+const (
+	sensitiveMaskValue          = "[REDACTED]"
+	defaultLogBufferSize        = 10000
+	defaultLogBatchSize         = 500
+	defaultLogFlushInterval     = time.Second
+	logInsertParameterThreshold = 60000
+)
+
+// ErrMissingHashSalt is returned when SensitiveHash is configured without the HashSalt,
+// the unsalted hash of the low entropy values (e.g. phone numbers) can be reversed by brute force
+var ErrMissingHashSalt = errors.New("sensitive hash mode requires the hash salt")
+
+// LogConfig represents the configuration for the log storage.
+type LogConfig struct {
+	Mode          LogWriterMode
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+	SensitiveMode SensitiveMode
+	HashSalt      string
+}
+
+// activityLogEntry is a single activity log waiting to be written along with its owner
+type activityLogEntry struct {
+	owner *int
+	log   *ActivityLog
+}
+
+// activityLogWriter writes the activity logs asynchronously in batches
+type activityLogWriter struct {
+	logStorage    *LogStorage
+	entries       chan *activityLogEntry
+	batchSize     int
+	flushInterval time.Duration
+	mu            sync.RWMutex
+	closed        bool
+	wg            sync.WaitGroup
+}
+
+func (w *activityLogWriter) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := []*activityLogEntry{}
+	for {
+		select {
+		case entry, ok := <-w.entries:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= w.batchSize {
+				w.flush(batch)
+				batch = []*activityLogEntry{}
+			}
+		case <-ticker.C:
+			w.flush(batch)
+			batch = []*activityLogEntry{}
+		}
+	}
+}
+
+func (w *activityLogWriter) flush(batch []*activityLogEntry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	err := w.logStorage.insertMany(w.logStorage.db, batch)
+	if err != nil {
+		fmt.Printf("\nError while write activitylog: %v\n", err)
+	}
+}
+
+// enqueue puts the entries into the buffer,
+// the entry is written directly when the buffer is full or the writer has been closed
+func (w *activityLogWriter) enqueue(entries []*activityLogEntry) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for _, entry := range entries {
+		if !w.closed {
+			select {
+			case w.entries <- entry:
+				continue
+			default:
+			}
+		}
+
+		w.flush([]*activityLogEntry{entry})
+	}
+}
+
+func (w *activityLogWriter) close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.entries)
+	w.mu.Unlock()
+
+	w.wg.Wait()
+}
+
+// pendingActivityLogs holds the activity logs of a running transaction
+// until the transaction is committed
+type pendingActivityLogs struct {
+	mu      sync.Mutex
+	writers []*activityLogWriter
+	entries [][]*activityLogEntry
+}
+
+func (p *pendingActivityLogs) add(writer *activityLogWriter, entries []*activityLogEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.writers = append(p.writers, writer)
+	p.entries = append(p.entries, entries)
+}
+
+func (p *pendingActivityLogs) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, writer := range p.writers {
+		writer.enqueue(p.entries[i])
+	}
+	p.writers = nil
+	p.entries = nil
+}
+
+func (p *pendingActivityLogs) discard() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.writers = nil
+	p.entries = nil
+}
+
+// withPendingActivityLogs prepares the context to hold the activity logs of a transaction
+func withPendingActivityLogs(ctx *context.Context) *context.Context {
+	*ctx = context.WithValue(*ctx, pendingLogsKey, &pendingActivityLogs{})
+	return ctx
+}
+
+func pendingActivityLogsFromContext(ctx *context.Context) (*pendingActivityLogs, bool) {
+	p, ok := (*ctx).Value(pendingLogsKey).(*pendingActivityLogs)
+	return p, ok
+}
+
+// write writes the entries according to the configured writer mode
+func (r *LogStorage) write(ctx *context.Context, entries ...*activityLogEntry) error {
+	tx, isInTransaction := TxFromContext(ctx)
+	if r.writer == nil {
+		db := r.db
+		if isInTransaction {
+			db = tx
+		}
+		return r.insertMany(db, entries)
+	}
+
+	if isInTransaction {
+		pending, ok := pendingActivityLogsFromContext(ctx)
+		if !ok {
+			// the transaction is not managed by the Manager, keep it consistent with the transaction
+			return r.insertMany(tx, entries)
+		}
+		pending.add(r.writer, entries)
+		return nil
+	}
+
+	r.writer.enqueue(entries)
+	return nil
+}
+
+// insertMany inserts the entries into the log table in batches
+func (r *LogStorage) insertMany(db Queryer, entries []*activityLogEntry) error {
+	limit := logInsertParameterThreshold / len(strings.Split(r.insertFields, ","))
+	for start := 0; start < len(entries); start += limit {
+		end := start + limit
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		sqlStr := fmt.Sprintf(`
+		INSERT INTO "%s"(%s)
+		VALUES `, r.logName, r.insertFields)
+		dbArgs := map[string]interface{}{}
+		for i, entry := range entries[start:end] {
+			sqlStr += fmt.Sprintf("(%s),", insertParams(r.elemType, true, i+1))
+			for k, v := range r.insertArgs(entry.owner, entry.log.UserID, entry.log, i+1) {
+				dbArgs[k] = v
+			}
+		}
+		sqlStr = strings.TrimSuffix(sqlStr, ",")
+
+		err := r.execNamed(db, sqlStr, dbArgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *LogStorage) execNamed(db Queryer, query string, args map[string]interface{}) error {
+	statement, err := db.PrepareNamed(query)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(args)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// redact returns the value to be stored in place of a sensitive value
+func (r *LogStorage) redact(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	if r.sensitiveMode == SensitiveHash {
+		mac := hmac.New(sha256.New, []byte(r.hashSalt))
+		mac.Write([]byte(fmt.Sprintf("%v", value)))
+		return fmt.Sprintf("hmac-sha256:%x", mac.Sum(nil))
+	}
+
+	return sensitiveMaskValue
+}
+
+// Close flushes the buffered activity logs and stops the async writer
+func (r *LogStorage) Close() {
+	if r.writer != nil {
+		r.writer.close()
+	}
+}
+
+// sensitiveField represents the keys of a field tagged `kit:"sensitive"` or `kit:"encrypted"`
+type sensitiveField struct {
+	jsonKey string
+	dbKey   string
+}
+
+func sensitiveFields(elemType reflect.Type) []sensitiveField {
+	fields := []sensitiveField{}
+	for i := 0; i < elemType.NumField(); i++ {
+		field := elemType.Field(i)
+		if !kitTag(field, "sensitive") && !kitTag(field, "encrypted") {
+			continue
+		}
+
+		jsonKey := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonKey == "" {
+			jsonKey = field.Name
+		}
+		fields = append(fields, sensitiveField{
+			jsonKey: jsonKey,
+			dbKey:   field.Tag.Get("db"),
+		})
+	}
+	return fields
+}
+
+// redactLog redacts the sensitive fields of the log before it's written
+func (r *PostgresStorage) redactLog(activityLog *ActivityLog) {
+	for _, field := range r.sensitiveFields {
+		if value, ok := activityLog.ValueBefore[field.jsonKey]; ok {
+			activityLog.ValueBefore[field.jsonKey] = r.logStorage.redact(value)
+		}
+		if value, ok := activityLog.ValueAfter[field.jsonKey]; ok {
+			activityLog.ValueAfter[field.jsonKey] = r.logStorage.redact(value)
+		}
+		if diff, ok := activityLog.Metadata[field.dbKey].([]interface{}); ok {
+			redactedDiff := make([]interface{}, len(diff))
+			for i, value := range diff {
+				redactedDiff[i] = r.logStorage.redact(value)
+			}
+			activityLog.Metadata[field.dbKey] = redactedDiff
+		}
+	}
+}
+
+// NewLogStorageWithConfig creates a logStorage with the given writer configuration,
+// it fails with ErrMissingHashSalt when SensitiveHash is configured without the HashSalt
+func NewLogStorageWithConfig(db *sqlx.DB, logName string, cfg LogConfig) (*LogStorage, error) {
+	if cfg.SensitiveMode == SensitiveHash && cfg.HashSalt == "" {
+		return nil, ErrMissingHashSalt
+	}
+
+	logStorage := NewLogStorage(db, logName)
+	logStorage.sensitiveMode = cfg.SensitiveMode
+	logStorage.hashSalt = cfg.HashSalt
+
+	if cfg.Mode != LogWriterAsync {
+		return logStorage, nil
+	}
+
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultLogBufferSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultLogBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultLogFlushInterval
+	}
+
+	logStorage.writer = &activityLogWriter{
+		logStorage:    logStorage,
+		entries:       make(chan *activityLogEntry, cfg.BufferSize),
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+	}
+	logStorage.writer.wg.Add(1)
+	go logStorage.writer.run()
+
+	return logStorage, nil
+}
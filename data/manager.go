@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/payfazz/commerce-kit/appcontext"
@@ -56,6 +57,10 @@ func (m *Manager) RunInTransaction(ctx *context.Context, f func(tctx *context.Co
 	}
 
 	ctx = NewContext(ctx, tx)
+	ctx = withPendingActivityLogs(ctx)
+	pendingLogs, _ := pendingActivityLogsFromContext(ctx)
+	ctx = withAfterCommitHooks(ctx)
+	hooks, _ := afterCommitHooksFromContext(ctx)
 	err = m.acknowledgeService.Prepare(ctx)
 	if err != nil {
 		fmt.Printf("\n[Commerce-Kit - RunInTransaction - Prepare] Error: %v\n", err)
@@ -63,21 +68,70 @@ func (m *Manager) RunInTransaction(ctx *context.Context, f func(tctx *context.Co
 	err = f(ctx)
 	if err != nil {
 		tx.Rollback()
+		pendingLogs.discard()
 		m.acknowledgeService.Acknowledge(ctx, "rollback", err.Error())
 		return err
 	}
 
 	err = tx.Commit()
 	if err != nil {
+		pendingLogs.discard()
 		m.acknowledgeService.Acknowledge(ctx, "rollback", fmt.Sprintf("Error when commiting: %s", err.Error()))
 		return fmt.Errorf("error when committing transaction: %v", err)
 	}
+	pendingLogs.flush()
+	hooks.run()
 	m.acknowledgeService.Acknowledge(ctx, "commit", "")
 	m.publishQueryModelEvents(ctx)
 
 	return nil
 }
 
+// afterCommitHooks holds the functions to be run after the transaction is committed
+type afterCommitHooks struct {
+	mu    sync.Mutex
+	hooks []func()
+}
+
+func (h *afterCommitHooks) add(f func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.hooks = append(h.hooks, f)
+}
+
+func (h *afterCommitHooks) run() {
+	h.mu.Lock()
+	hooks := h.hooks
+	h.hooks = nil
+	h.mu.Unlock()
+
+	for _, f := range hooks {
+		f()
+	}
+}
+
+func withAfterCommitHooks(ctx *context.Context) *context.Context {
+	*ctx = context.WithValue(*ctx, afterCommitKey, &afterCommitHooks{})
+	return ctx
+}
+
+func afterCommitHooksFromContext(ctx *context.Context) (*afterCommitHooks, bool) {
+	h, ok := (*ctx).Value(afterCommitKey).(*afterCommitHooks)
+	return h, ok
+}
+
+// AfterCommit registers the f to be run after the transaction of the context managed by RunInTransaction
+// is committed, the f is discarded on rollback. It returns false when there is no such transaction.
+func AfterCommit(ctx *context.Context, f func()) bool {
+	hooks, ok := afterCommitHooksFromContext(ctx)
+	if !ok {
+		return false
+	}
+	hooks.add(f)
+	return true
+}
+
 // NewManager creates a new manager
 func NewManager(
 	db *sqlx.DB,
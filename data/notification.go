@@ -0,0 +1,60 @@
+package data
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/payfazz/commerce-kit/data/notify"
+)
+
+// notifyChanges sends the change notifications of the rows to the configured channel,
+// the notifications are delivered when the transaction is committed.
+// Outside of a transaction the rows are already written, so the error is only logged.
+func (r *PostgresStorage) notifyChanges(ctx *context.Context, operation notify.Operation, ids []int) error {
+	if r.notifyChannel == "" || len(ids) == 0 {
+		return nil
+	}
+
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		err := r.sendNotifications(r.db, operation, ids)
+		if err != nil {
+			fmt.Printf("\nError while send change notifications: %v\n", err)
+		}
+		return nil
+	}
+	return r.sendNotifications(tx, operation, ids)
+}
+
+func (r *PostgresStorage) sendNotifications(db Queryer, operation notify.Operation, ids []int) error {
+	payloads := []string{}
+	for _, id := range ids {
+		payload, err := notify.Payload(r.tableName, id, operation)
+		if err != nil {
+			return err
+		}
+		payloads = append(payloads, payload)
+	}
+
+	statement, err := db.PrepareNamed(`SELECT pg_notify(:channel, "payload") FROM unnest(cast(:payloads as text[])) AS "payload"`)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(map[string]interface{}{
+		"channel":  r.notifyChannel,
+		"payloads": pq.Array(payloads),
+	})
+	return err
+}
+
+// notifyUpdatedRows sends the update notifications of the rows
+func (r *PostgresStorage) notifyUpdatedRows(ctx *context.Context, ids []interface{}) error {
+	updatedIDs := []int{}
+	for _, id := range ids {
+		updatedIDs = append(updatedIDs, referenceID(id))
+	}
+	return r.notifyChanges(ctx, notify.OperationUpdate, updatedIDs)
+}
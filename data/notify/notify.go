@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DefaultChannel is the postgres channel used when no channel is configured
+const DefaultChannel = "commerce_kit_changes"
+
+const (
+	defaultMinReconnectInterval = 10 * time.Second
+	defaultMaxReconnectInterval = time.Minute
+	defaultPingInterval         = 90 * time.Second
+)
+
+// Operation represents the change operation of a row
+type Operation string
+
+// Enum value for operation
+const (
+	OperationInsert     Operation = "insert"
+	OperationUpdate     Operation = "update"
+	OperationDelete     Operation = "delete"
+	OperationHardDelete Operation = "hardDelete"
+	// OperationReconnect is dispatched to every subscriber after the listener reconnects,
+	// the notifications sent while disconnected are lost so the subscribers should invalidate everything
+	OperationReconnect Operation = "reconnect"
+)
+
+// Event represents a change notification of a row
+type Event struct {
+	Table     string    `json:"table"`
+	ID        int       `json:"id"`
+	Operation Operation `json:"operation"`
+}
+
+// Payload returns the notification payload of the changed row
+func Payload(table string, id int, operation Operation) (string, error) {
+	payload, err := json.Marshal(&Event{
+		Table:     table,
+		ID:        id,
+		Operation: operation,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+// Handler handles the change event
+type Handler func(event *Event)
+
+// ListenerConfig represents the configuration for the listener
+type ListenerConfig struct {
+	Channel              string
+	MinReconnectInterval time.Duration
+	MaxReconnectInterval time.Duration
+	PingInterval         time.Duration
+}
+
+// Listener listens to the change notifications and dispatches them to the subscribers
+type Listener struct {
+	listener     *pq.Listener
+	channel      string
+	pingInterval time.Duration
+	mu           sync.RWMutex
+	subscribers  map[string]map[int]Handler
+	lastID       int
+	done         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Subscribe registers the handler for the changes of the table, empty table means all tables.
+// It returns the function to unsubscribe the handler.
+func (l *Listener) Subscribe(table string, handler Handler) func() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastID++
+	id := l.lastID
+	if l.subscribers[table] == nil {
+		l.subscribers[table] = map[int]Handler{}
+	}
+	l.subscribers[table][id] = handler
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.subscribers[table], id)
+	}
+}
+
+func (l *Listener) dispatch(event *Event) {
+	l.mu.RLock()
+	handlers := []Handler{}
+	for table, subscribers := range l.subscribers {
+		if table != "" && table != event.Table && event.Operation != OperationReconnect {
+			continue
+		}
+		for _, handler := range subscribers {
+			handlers = append(handlers, handler)
+		}
+	}
+	l.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+func (l *Listener) run() {
+	defer l.wg.Done()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case notification := <-l.listener.Notify:
+			if notification == nil {
+				// the connection has been re-established
+				l.dispatch(&Event{Operation: OperationReconnect})
+				continue
+			}
+
+			event := &Event{}
+			err := json.Unmarshal([]byte(notification.Extra), event)
+			if err != nil {
+				log.Printf("[Notify Listener] Invalid payload %s: %v", notification.Extra, err)
+				continue
+			}
+			l.dispatch(event)
+		case <-time.After(l.pingInterval):
+			go l.listener.Ping()
+		}
+	}
+}
+
+// Close stops listening to the notifications
+func (l *Listener) Close() error {
+	close(l.done)
+	l.wg.Wait()
+	return l.listener.Close()
+}
+
+// NewListener creates a listener connected to the database of the dsn
+func NewListener(dsn string, cfg ListenerConfig) (*Listener, error) {
+	if cfg.Channel == "" {
+		cfg.Channel = DefaultChannel
+	}
+	if cfg.MinReconnectInterval <= 0 {
+		cfg.MinReconnectInterval = defaultMinReconnectInterval
+	}
+	if cfg.MaxReconnectInterval <= 0 {
+		cfg.MaxReconnectInterval = defaultMaxReconnectInterval
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = defaultPingInterval
+	}
+
+	listener := pq.NewListener(dsn, cfg.MinReconnectInterval, cfg.MaxReconnectInterval, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("[Notify Listener] Connection event %d: %v", ev, err)
+		}
+	})
+
+	err := listener.Listen(cfg.Channel)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("error when listening to %s: %v", cfg.Channel, err)
+	}
+
+	l := &Listener{
+		listener:     listener,
+		channel:      cfg.Channel,
+		pingInterval: cfg.PingInterval,
+		subscribers:  map[string]map[int]Handler{},
+		done:         make(chan struct{}),
+	}
+	l.wg.Add(1)
+	go l.run()
+
+	return l, nil
+}
@@ -0,0 +1,161 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/payfazz/commerce-kit/data/notify"
+)
+
+// ErrInvalidField is returned when the field is not an updatable db field of the model
+var ErrInvalidField = fmt.Errorf("invalid field")
+
+// ErrPatchNotSupported is returned when the storage doesn't implement PatchStorage
+var ErrPatchNotSupported = fmt.Errorf("storage doesn't support the partial update")
+
+// PatchStorage represents the storage updating only the given columns of the element,
+// it's implemented by PostgresStorage
+type PatchStorage interface {
+	UpdateFields(ctx *context.Context, id interface{}, fields map[string]interface{}) error
+	UpdateWithMask(ctx *context.Context, elem interface{}, fields []string) error
+}
+
+// updatableField returns the struct field index of the updatable db tag
+func (r *PostgresStorage) updatableField(dbTag string) (int, bool) {
+	if readOnlyTag(dbTag) || emptyTag(dbTag) {
+		return 0, false
+	}
+	for i := 0; i < r.elemType.NumField(); i++ {
+		if r.elemType.Field(i).Tag.Get("db") == dbTag {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// UpdateFields updates only the given columns of the element by its id.
+// The keys of the fields are the db tags of the model.
+func (r *PostgresStorage) UpdateFields(ctx *context.Context, id interface{}, fields map[string]interface{}) error {
+	elem := reflect.New(r.elemType).Interface()
+	return r.updateMasked(ctx, id, fields, elem)
+}
+
+// UpdateWithMask updates only the masked columns of the element with its values.
+// The fields are the db tags of the model.
+func (r *PostgresStorage) UpdateWithMask(ctx *context.Context, elem interface{}, fields []string) error {
+	v := reflect.ValueOf(elem).Elem()
+	values := map[string]interface{}{}
+	for _, field := range fields {
+		i, ok := r.updatableField(field)
+		if !ok {
+			return ErrInvalidField
+		}
+		values[field] = v.Field(i).Interface()
+	}
+
+	return r.updateMasked(ctx, r.findID(elem), values, elem)
+}
+
+// updateMasked sets only the columns of the values and scans the updated row into the elem
+func (r *PostgresStorage) updateMasked(ctx *context.Context, id interface{}, values map[string]interface{}, elem interface{}) error {
+	currentUserID, currentUserType := determineUser(ctx)
+	updateArgs := map[string]interface{}{
+		"updatedAt": time.Now().UTC(),
+		"updatedBy": currentUserID,
+	}
+	setFields := []string{"\"updatedAt\" = :updatedAt", "\"updatedBy\" = :updatedBy"}
+	for dbTag, value := range values {
+		i, ok := r.updatableField(dbTag)
+		if !ok {
+			return ErrInvalidField
+		}
+
+		if value != nil && reflect.TypeOf(value) == reflect.TypeOf(map[string]interface{}{}) {
+			metadataBytes, err := json.Marshal(value)
+			if err != nil {
+				value = "{}"
+			} else {
+				value = string(metadataBytes)
+			}
+		}
+		updateArgs[dbTag] = value
+		setFields = append(setFields, fmt.Sprintf("\"%s\" = :%s", dbTag, dbTag))
+		if blindIndex := blindIndexColumn(r.elemType.Field(i)); blindIndex != "" {
+			setFields = append(setFields, fmt.Sprintf("\"%s\" = :%s", blindIndex, blindIndex))
+		}
+	}
+
+	err := r.encryptArgs(updateArgs)
+	if err != nil {
+		return err
+	}
+
+	// the lock, the update & its log share the transaction, a new one is begun outside of a transaction
+	return runInTx(ctx, r.db, func(tctx *context.Context, db Queryer) error {
+		// the row is locked until the update so the before-image isn't changed by the concurrent update
+		existingElem := reflect.New(r.elemType).Interface()
+		err := r.Single(tctx, existingElem, `"id" = :id FOR UPDATE`, map[string]interface{}{
+			"id": id,
+		})
+		if err != nil {
+			return err
+		}
+
+		statement, err := db.PrepareNamed(fmt.Sprintf(`
+			UPDATE "%s" SET %s WHERE "id" = :id RETURNING %s`,
+			r.tableName,
+			strings.Join(setFields, ","),
+			r.selectFields))
+		if err != nil {
+			return err
+		}
+		defer statement.Close()
+
+		updateArgs["id"] = id
+		err = statement.Get(elem, updateArgs)
+		if err != nil {
+			return err
+		}
+		err = r.decryptResult(elem)
+		if err != nil {
+			return err
+		}
+
+		elemID := referenceID(r.findID(elem))
+		err = r.notifyChanges(tctx, notify.OperationUpdate, []int{elemID})
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+
+		valueBefore, err := interfaceConversion(existingElem)
+		if err != nil {
+			return err
+		}
+		valueAfter, err := interfaceConversion(elem)
+		if err != nil {
+			return err
+		}
+
+		err = r.createLog(tctx, &ActivityLog{
+			UserID:          currentUserID,
+			UserType:        currentUserType,
+			TableName:       r.tableName,
+			ReferenceID:     elemID,
+			Metadata:        r.findChanges(existingElem, elem),
+			ValueBefore:     valueBefore,
+			ValueAfter:      valueAfter,
+			TransactionTime: &now,
+			TransactionType: "Update",
+		})
+		if err != nil {
+			fmt.Printf("\nError while write activitylog: %v\n", err)
+		}
+
+		return nil
+	})
+}
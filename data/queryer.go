@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -10,7 +11,9 @@ import (
 type key int
 
 const (
-	txKey key = 0
+	txKey          key = 0
+	pendingLogsKey key = 1
+	afterCommitKey key = 2
 )
 
 // Queryer represents the database commands interface
@@ -19,6 +22,7 @@ type Queryer interface {
 	Rebind(query string) string
 	MustExec(query string, args ...interface{}) sql.Result
 	Select(dest interface{}, query string, args ...interface{}) error
+	Queryx(query string, args ...interface{}) (*sqlx.Rows, error)
 	Get(dest interface{}, query string, args ...interface{}) error
 }
 
@@ -33,3 +37,40 @@ func TxFromContext(ctx *context.Context) (Queryer, bool) {
 	q, ok := (*ctx).Value(txKey).(Queryer)
 	return q, ok
 }
+
+// runInTx runs the f in the transaction of the context, or in a new transaction of the db when there is none.
+// The after commit hooks registered in the new transaction are run after it's committed.
+func runInTx(ctx *context.Context, db Queryer, f func(tctx *context.Context, tx Queryer) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		return f(ctx, tx)
+	}
+
+	beginner, ok := db.(interface {
+		Beginx() (*sqlx.Tx, error)
+	})
+	if !ok {
+		return f(ctx, db)
+	}
+
+	tx, err := beginner.Beginx()
+	if err != nil {
+		return fmt.Errorf("error when creating transction: %v", err)
+	}
+
+	tctx := *ctx
+	NewContext(&tctx, tx)
+	withAfterCommitHooks(&tctx)
+	hooks, _ := afterCommitHooksFromContext(&tctx)
+	err = f(&tctx, tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error when committing transaction: %v", err)
+	}
+	hooks.run()
+	return nil
+}
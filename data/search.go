@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSearchColumn is returned when the searched column is not a searchable db field of the model
+var ErrInvalidSearchColumn = fmt.Errorf("invalid search column")
+
+// ErrSearchLanguageMismatch is returned when the SearchVectorColumn is searched in another language than its SearchLanguage
+var ErrSearchLanguageMismatch = fmt.Errorf("search language mismatch")
+
+const (
+	// SearchVectorColumn is the name of the generated tsvector column declared by SearchColumnQuery
+	SearchVectorColumn    = "searchVector"
+	defaultSearchLanguage = "simple"
+	defaultSearchLimit    = 100
+)
+
+// SearchOptions represents the options for the full-text search.
+// Empty Columns means the search uses the generated SearchVectorColumn & its GIN index,
+// the Language then defaults to the SearchLanguage of the storage and must match it.
+// The explicit Columns are not indexed, their tsvector is computed per row by a sequential scan.
+type SearchOptions struct {
+	Columns  []string
+	Language string
+	Limit    int
+}
+
+// tsvectorExpression returns the tsvector of the concatenated columns
+func tsvectorExpression(language string, columns []string) string {
+	values := []string{}
+	for _, column := range columns {
+		values = append(values, fmt.Sprintf(`coalesce(cast("%s" as text), '')`, column))
+	}
+	return fmt.Sprintf(`to_tsvector(%s, %s)`, language, strings.Join(values, ` || ' ' || `))
+}
+
+// searchVector returns the tsvector expression of the search options
+func (r *PostgresStorage) searchVector(opts SearchOptions) (string, error) {
+	if len(opts.Columns) == 0 {
+		return fmt.Sprintf(`"%s"`, SearchVectorColumn), nil
+	}
+
+	fields := map[string]bool{}
+	for i := 0; i < r.elemType.NumField(); i++ {
+		dbTag := r.elemType.Field(i).Tag.Get("db")
+		if dbTag != "" && dbTag != "-" {
+			fields[dbTag] = true
+		}
+	}
+
+	for _, column := range opts.Columns {
+		if _, ok := r.encryptedField(column); ok || !fields[column] {
+			return "", ErrInvalidSearchColumn
+		}
+	}
+
+	return tsvectorExpression(`cast(:language as regconfig)`, opts.Columns), nil
+}
+
+// Search finds the elements matching the web search query ordered by its rank.
+// It keeps the owner & soft delete scoping of Where.
+func (r *PostgresStorage) Search(ctx *context.Context, elems interface{}, query string, opts SearchOptions) error {
+	if opts.Language == "" {
+		opts.Language = r.searchLanguage
+	}
+	if len(opts.Columns) == 0 && opts.Language != r.searchLanguage {
+		return ErrSearchLanguageMismatch
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultSearchLimit
+	}
+
+	vector, err := r.searchVector(opts)
+	if err != nil {
+		return err
+	}
+
+	tsquery := `websearch_to_tsquery(cast(:language as regconfig), :query)`
+	where := fmt.Sprintf(`%s @@ %s ORDER BY ts_rank(%s, %s) DESC, "id" LIMIT :limit`, vector, tsquery, vector, tsquery)
+
+	return r.Where(ctx, elems, where, map[string]interface{}{
+		"language": opts.Language,
+		"query":    query,
+		"limit":    opts.Limit,
+	})
+}
+
+// SearchColumnQuery returns the query to declare the generated SearchVectorColumn & its GIN index,
+// it's meant to be used in the migration. The language is the SearchLanguage of the PostgresConfig.
+func SearchColumnQuery(tableName string, language string, columns ...string) string {
+	if language == "" {
+		language = defaultSearchLanguage
+	}
+
+	return fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN IF NOT EXISTS "%s" tsvector
+	GENERATED ALWAYS AS (%s) STORED;
+CREATE INDEX IF NOT EXISTS "%s_%s_idx" ON "%s" USING GIN ("%s");`,
+		tableName, SearchVectorColumn, tsvectorExpression(fmt.Sprintf(`'%s'`, language), columns),
+		tableName, SearchVectorColumn, tableName, SearchVectorColumn)
+}
+
+// DropSearchColumnQuery returns the query to drop the SearchVectorColumn & its index,
+// it's meant to be used in the down migration
+func DropSearchColumnQuery(tableName string) string {
+	return fmt.Sprintf(`DROP INDEX IF EXISTS "%s_%s_idx";
+ALTER TABLE "%s" DROP COLUMN IF EXISTS "%s";`,
+		tableName, SearchVectorColumn, tableName, SearchVectorColumn)
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	"github.com/payfazz/commerce-kit/appcontext"
+	"github.com/payfazz/commerce-kit/data/notify"
 	"github.com/payfazz/commerce-kit/types"
 )
 
@@ -72,21 +73,34 @@ type PostgresStorage struct {
 	updateSetFields        string
 	updateManySetFields    string
 	updateManyAsFields     string
+	sensitiveFields        []sensitiveField
+	encryptedFields        []encryptedField
+	keyProvider            KeyProvider
+	notifyChannel          string
+	searchLanguage         string
 	logStorage             LogStorage
 }
 
 // LogStorage storage for logs
 type LogStorage struct {
-	db           Queryer
-	logName      string
-	elemType     reflect.Type
-	insertFields string
-	insertParams string
+	db            Queryer
+	logName       string
+	elemType      reflect.Type
+	insertFields  string
+	insertParams  string
+	writer        *activityLogWriter
+	sensitiveMode SensitiveMode
+	hashSalt      string
 }
 
 // PostgresConfig represents the configuration for the postgres Storage.
 type PostgresConfig struct {
-	IsImmutable bool
+	IsImmutable   bool
+	KeyProvider   KeyProvider
+	Notify        bool
+	NotifyChannel string
+	// SearchLanguage is the language of the SearchVectorColumn passed to SearchColumnQuery
+	SearchLanguage string
 }
 
 // Single queries an element according to the query & argument provided
@@ -121,7 +135,7 @@ func (r *PostgresStorage) Single(ctx *context.Context, elem interface{}, where s
 		return err
 	}
 
-	return nil
+	return r.decryptResult(elem)
 }
 
 // SinglePOSTEMP queries an element according to the query & argument provided
@@ -156,7 +170,7 @@ func (r *PostgresStorage) SinglePOSTEMP(ctx *context.Context, elem interface{},
 		return err
 	}
 
-	return nil
+	return r.decryptResult(elem)
 }
 
 // Where queries the elements according to the query & argument provided
@@ -194,7 +208,7 @@ func (r *PostgresStorage) Where(ctx *context.Context, elems interface{}, where s
 		return err
 	}
 
-	return nil
+	return r.decryptResult(elems)
 }
 
 // WherePOSTEMP queries the elements according to the query & argument provided
@@ -232,7 +246,7 @@ func (r *PostgresStorage) WherePOSTEMP(ctx *context.Context, elems interface{},
 		return err
 	}
 
-	return nil
+	return r.decryptResult(elems)
 }
 
 // SelectWithQuery Customizable Query for Select
@@ -263,7 +277,7 @@ func (r *PostgresStorage) SelectWithQuery(ctx *context.Context, elems interface{
 		return err
 	}
 
-	return nil
+	return r.decryptResult(elems)
 }
 
 // FindByID finds an element by its id
@@ -341,13 +355,24 @@ func (r *PostgresStorage) Insert(ctx *context.Context, elem interface{}) error {
 	}
 	defer statement.Close()
 
-	dbArgs := r.insertArgs(currentAccount, currentUserID, elem, 0)
+	dbArgs, err := r.insertArgs(currentAccount, currentUserID, elem, 0)
+	if err != nil {
+		return err
+	}
 	err = statement.Get(elem, dbArgs)
 	if err != nil {
 		return err
 	}
+	err = r.decryptResult(elem)
+	if err != nil {
+		return err
+	}
 
 	elemID := r.findID(elem)
+	err = r.notifyChanges(ctx, notify.OperationInsert, []int{referenceID(elemID)})
+	if err != nil {
+		return err
+	}
 	now := time.Now()
 
 	valueAfter, err := interfaceConversion(elem)
@@ -372,7 +397,7 @@ func (r *PostgresStorage) Insert(ctx *context.Context, elem interface{}) error {
 	return nil
 }
 
-func (r *PostgresStorage) insertArgs(currentAccount *int, currentUserID int, elem interface{}, index int) map[string]interface{} {
+func (r *PostgresStorage) insertArgs(currentAccount *int, currentUserID int, elem interface{}, index int) (map[string]interface{}, error) {
 	res := map[string]interface{}{
 		"owner":     currentAccount,
 		"createdAt": time.Now().UTC(),
@@ -411,12 +436,17 @@ func (r *PostgresStorage) insertArgs(currentAccount *int, currentUserID int, ele
 		}
 	}
 
+	err := r.encryptArgs(res)
+	if err != nil {
+		return nil, err
+	}
+
 	if index != 0 {
 		s := strconv.Itoa(index)
 		res = renamingKey(res, s)
 	}
 
-	return res
+	return res, nil
 }
 
 func (r *LogStorage) insertArgs(currentAccount *int, currentUserID int, elem interface{}, index int) map[string]interface{} {
@@ -466,11 +496,6 @@ func (r *LogStorage) insertArgs(currentAccount *int, currentUserID int, elem int
 func (r *PostgresStorage) InsertMany(ctx *context.Context, elem interface{}) error {
 	currentAccount := appcontext.CurrentAccount(ctx)
 	currentUserID, _ := determineUser(ctx)
-	db := r.db
-	tx, ok := TxFromContext(ctx)
-	if ok {
-		db = tx
-	}
 
 	sqlStr := fmt.Sprintf(`
 	INSERT INTO "%s"(%s)
@@ -486,7 +511,10 @@ func (r *PostgresStorage) InsertMany(ctx *context.Context, elem interface{}) err
 	if datas.Kind() == reflect.Slice {
 		for i := 0; i < datas.Len(); i++ {
 			sqlStr += fmt.Sprintf("(%s),", insertParams(r.elemType, r.isImmutable, i+1))
-			arg := r.insertArgs(currentAccount, currentUserID, datas.Index(i), i+1)
+			arg, err := r.insertArgs(currentAccount, currentUserID, datas.Index(i), i+1)
+			if err != nil {
+				return err
+			}
 			if indexData == 0 {
 				dbArgs = arg
 			} else {
@@ -513,7 +541,10 @@ func (r *PostgresStorage) InsertMany(ctx *context.Context, elem interface{}) err
 	if datas.Kind() == reflect.Map {
 		for key, element := range datas.MapKeys() {
 			sqlStr += fmt.Sprintf("(%s),", insertParams(r.elemType, r.isImmutable, key+1))
-			arg := r.insertArgs(currentAccount, currentUserID, datas.MapIndex(element), key+1)
+			arg, err := r.insertArgs(currentAccount, currentUserID, datas.MapIndex(element), key+1)
+			if err != nil {
+				return err
+			}
 			if indexData == 0 {
 				dbArgs = arg
 			} else {
@@ -537,32 +568,13 @@ func (r *PostgresStorage) InsertMany(ctx *context.Context, elem interface{}) err
 		}
 	}
 
-	sqlStr = strings.TrimSuffix(sqlStr, ",")
-	sqlStr += fmt.Sprintf(" RETURNING %s", r.selectFields)
-
-	statement, err := db.PrepareNamed(sqlStr)
-	if err != nil {
-		return err
-	}
-	defer statement.Close()
-
-	_, err = statement.Exec(dbArgs)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return r.insertData(ctx, sqlStr, dbArgs)
 }
 
 // InsertManyWithResult is function for creating many datas into specific table in database.
 func (r *PostgresStorage) InsertManyWithResult(ctx *context.Context, elem interface{}, result interface{}) error {
 	currentAccount := appcontext.CurrentAccount(ctx)
 	currentUserID, _ := determineUser(ctx)
-	db := r.db
-	tx, ok := TxFromContext(ctx)
-	if ok {
-		db = tx
-	}
 
 	sqlStr := fmt.Sprintf(`
 	INSERT INTO "%s"(%s)
@@ -578,7 +590,10 @@ func (r *PostgresStorage) InsertManyWithResult(ctx *context.Context, elem interf
 	if datas.Kind() == reflect.Slice {
 		for i := 0; i < datas.Len(); i++ {
 			sqlStr += fmt.Sprintf("(%s),", insertParams(r.elemType, r.isImmutable, i+1))
-			arg := r.insertArgs(currentAccount, currentUserID, datas.Index(i), i+1)
+			arg, err := r.insertArgs(currentAccount, currentUserID, datas.Index(i), i+1)
+			if err != nil {
+				return err
+			}
 			if indexData == 0 {
 				dbArgs = arg
 			} else {
@@ -605,7 +620,10 @@ func (r *PostgresStorage) InsertManyWithResult(ctx *context.Context, elem interf
 	if datas.Kind() == reflect.Map {
 		for key, element := range datas.MapKeys() {
 			sqlStr += fmt.Sprintf("(%s),", insertParams(r.elemType, r.isImmutable, key+1))
-			arg := r.insertArgs(currentAccount, currentUserID, datas.MapIndex(element), key+1)
+			arg, err := r.insertArgs(currentAccount, currentUserID, datas.MapIndex(element), key+1)
+			if err != nil {
+				return err
+			}
 			if indexData == 0 {
 				dbArgs = arg
 			} else {
@@ -629,21 +647,7 @@ func (r *PostgresStorage) InsertManyWithResult(ctx *context.Context, elem interf
 		}
 	}
 
-	sqlStr = strings.TrimSuffix(sqlStr, ",")
-	sqlStr += fmt.Sprintf(" RETURNING %s", r.selectFields)
-
-	statement, err := db.PrepareNamed(sqlStr)
-	if err != nil {
-		return err
-	}
-	defer statement.Close()
-
-	err = statement.Select(result, dbArgs)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return r.insertDataWithResult(ctx, sqlStr, dbArgs, result)
 }
 
 // InsertManyWithTime is function for creating many datas into specific table in database with specific createdAt.
@@ -666,7 +670,10 @@ func (r *PostgresStorage) InsertManyWithTime(ctx *context.Context, elem interfac
 		for i := 0; i < datas.Len(); i++ {
 			sqlStr += fmt.Sprintf("(%s),", insertParams(r.elemType, r.isImmutable, i+1))
 
-			arg := r.insertArgs(currentAccount, currentUserID, datas.Index(i), i+1)
+			arg, err := r.insertArgs(currentAccount, currentUserID, datas.Index(i), i+1)
+			if err != nil {
+				return err
+			}
 			arg[fmt.Sprintf("createdAt%d", i+1)] = createdAt
 			if indexData == 0 {
 				dbArgs = arg
@@ -694,7 +701,10 @@ func (r *PostgresStorage) InsertManyWithTime(ctx *context.Context, elem interfac
 	if datas.Kind() == reflect.Map {
 		for key, element := range datas.MapKeys() {
 			sqlStr += fmt.Sprintf("(%s),", insertParams(r.elemType, r.isImmutable, key+1))
-			arg := r.insertArgs(currentAccount, currentUserID, datas.MapIndex(element), key+1)
+			arg, err := r.insertArgs(currentAccount, currentUserID, datas.MapIndex(element), key+1)
+			if err != nil {
+				return err
+			}
 			arg[fmt.Sprintf("createdAt%d", key+1)] = createdAt
 			if indexData == 0 {
 				dbArgs = arg
@@ -735,6 +745,7 @@ func (r *PostgresStorage) insertData(ctx *context.Context, sqlStr string, dbArgs
 	}
 
 	sqlStr = strings.TrimSuffix(sqlStr, ",")
+	sqlStr += fmt.Sprintf(" RETURNING %s", r.selectFields)
 
 	statement, err := db.PrepareNamed(sqlStr)
 	if err != nil {
@@ -742,11 +753,23 @@ func (r *PostgresStorage) insertData(ctx *context.Context, sqlStr string, dbArgs
 	}
 	defer statement.Close()
 
-	_, err = statement.Exec(dbArgs)
+	elems := r.newElems()
+	err = statement.Select(elems, dbArgs)
+	if err != nil {
+		return err
+	}
+
+	valuesAfter, ids := r.affectedRows(elems)
+	err = r.notifyChanges(ctx, notify.OperationInsert, ids)
 	if err != nil {
 		return err
 	}
 
+	err = r.createLogs(ctx, "Insert", ids, nil, valuesAfter)
+	if err != nil {
+		fmt.Printf("\nError while write activitylog: %v\n", err)
+	}
+
 	return nil
 }
 
@@ -771,6 +794,22 @@ func (r *PostgresStorage) insertDataWithResult(ctx *context.Context, sqlStr stri
 		return err
 	}
 
+	err = r.decryptResult(result)
+	if err != nil {
+		return err
+	}
+
+	valuesAfter, ids := r.affectedRows(result)
+	err = r.notifyChanges(ctx, notify.OperationInsert, ids)
+	if err != nil {
+		return err
+	}
+
+	err = r.createLogs(ctx, "Insert", ids, nil, valuesAfter)
+	if err != nil {
+		fmt.Printf("\nError while write activitylog: %v\n", err)
+	}
+
 	return nil
 }
 
@@ -831,13 +870,21 @@ func (r *PostgresStorage) Update(ctx *context.Context, elem interface{}) error {
 	}
 	defer statement.Close()
 
-	updateArgs := r.updateArgs(currentUserID, existingElem, elem)
+	updateArgs, err := r.updateArgs(currentUserID, existingElem, elem)
+	if err != nil {
+		return err
+	}
 	updateArgs["id"] = id
 	err = statement.Get(elem, updateArgs)
 	if err != nil {
 		return err
 	}
+	err = r.decryptResult(elem)
+	if err != nil {
+		return err
+	}
 	elemID := r.findID(elem)
+	err = r.notifyChanges(ctx, notify.OperationUpdate, []int{referenceID(elemID)})
 	if err != nil {
 		return err
 	}
@@ -874,13 +921,9 @@ func (r *PostgresStorage) Update(ctx *context.Context, elem interface{}) error {
 // It will update the "updatedAt" field.
 func (r *PostgresStorage) UpdateMany(ctx *context.Context, elems interface{}) error {
 	currentUserID, _ := determineUser(ctx)
-	db := r.db
-	tx, ok := TxFromContext(ctx)
-	if ok {
-		db = tx
-	}
 
 	dbArgs := map[string]interface{}{}
+	ids := []interface{}{}
 
 	sqlStr := fmt.Sprintf(`
 	UPDATE "%s" as "currentTable" 
@@ -895,8 +938,12 @@ func (r *PostgresStorage) UpdateMany(ctx *context.Context, elems interface{}) er
 	indexData := 0
 	if datas.Kind() == reflect.Slice {
 		for i := 0; i < datas.Len(); i++ {
-			sqlStrIndex, arg := r.updateManyParams(currentUserID, datas.Index(i), i+1)
+			sqlStrIndex, arg, err := r.updateManyParams(currentUserID, datas.Index(i), i+1)
+			if err != nil {
+				return err
+			}
 			sqlStr += sqlStrIndex
+			ids = append(ids, r.findElemID(datas.Index(i)))
 			if indexData == 0 {
 				dbArgs = arg
 			} else {
@@ -906,7 +953,7 @@ func (r *PostgresStorage) UpdateMany(ctx *context.Context, elems interface{}) er
 			}
 			indexData++
 			if indexData == limit {
-				err := r.updateData(ctx, sqlStr, dbArgs)
+				err := r.updateData(ctx, sqlStr, dbArgs, ids)
 				if err != nil {
 					return err
 				}
@@ -919,14 +966,19 @@ func (r *PostgresStorage) UpdateMany(ctx *context.Context, elems interface{}) er
 				FROM (VALUES
 				`, r.tableName, r.updateManySetFields)
 				dbArgs = map[string]interface{}{}
+				ids = []interface{}{}
 			}
 		}
 	}
 
 	if datas.Kind() == reflect.Map {
 		for key, element := range datas.MapKeys() {
-			sqlStrIndex, arg := r.updateManyParams(currentUserID, datas.MapIndex(element), key+1)
+			sqlStrIndex, arg, err := r.updateManyParams(currentUserID, datas.MapIndex(element), key+1)
+			if err != nil {
+				return err
+			}
 			sqlStr += sqlStrIndex
+			ids = append(ids, r.findElemID(datas.MapIndex(element)))
 			if indexData == 0 {
 				dbArgs = arg
 			} else {
@@ -936,7 +988,7 @@ func (r *PostgresStorage) UpdateMany(ctx *context.Context, elems interface{}) er
 			}
 			indexData++
 			if indexData == limit {
-				err := r.updateData(ctx, sqlStr, dbArgs)
+				err := r.updateData(ctx, sqlStr, dbArgs, ids)
 				if err != nil {
 					return err
 				}
@@ -949,32 +1001,15 @@ func (r *PostgresStorage) UpdateMany(ctx *context.Context, elems interface{}) er
 				FROM (VALUES
 				`, r.tableName, r.updateManySetFields)
 				dbArgs = map[string]interface{}{}
+				ids = []interface{}{}
 			}
 		}
 	}
 
-	sqlStr = strings.TrimSuffix(sqlStr, ",")
-
-	sqlStr = fmt.Sprintf(`%s
-	) as "updatedTable"("updatedAt", "updatedBy", %s)
-	where cast("currentTable".id as int) = cast("updatedTable".id as int)
-	`, sqlStr, r.updateManyAsFields)
-
-	statement, err := db.PrepareNamed(sqlStr)
-	if err != nil {
-		return err
-	}
-	defer statement.Close()
-
-	_, err = statement.Exec(dbArgs)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return r.updateData(ctx, sqlStr, dbArgs, ids)
 }
 
-func (r *PostgresStorage) updateData(ctx *context.Context, sqlStr string, dbArgs map[string]interface{}) error {
+func (r *PostgresStorage) updateData(ctx *context.Context, sqlStr string, dbArgs map[string]interface{}, ids []interface{}) error {
 	db := r.db
 	tx, ok := TxFromContext(ctx)
 	if ok {
@@ -988,6 +1023,11 @@ func (r *PostgresStorage) updateData(ctx *context.Context, sqlStr string, dbArgs
 	where cast("currentTable".id as int) = cast("updatedTable".id as int)
 	`, sqlStr, r.updateManyAsFields)
 
+	valuesBefore, err := r.findByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
 	statement, err := db.PrepareNamed(sqlStr)
 	if err != nil {
 		return err
@@ -999,6 +1039,13 @@ func (r *PostgresStorage) updateData(ctx *context.Context, sqlStr string, dbArgs
 		return err
 	}
 
+	err = r.notifyUpdatedRows(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	r.logUpdatedRows(ctx, ids, valuesBefore, nil)
+
 	return nil
 }
 
@@ -1006,13 +1053,9 @@ func (r *PostgresStorage) updateData(ctx *context.Context, sqlStr string, dbArgs
 // It will update the "updatedAt" field.
 func (r *PostgresStorage) UpdateManyWithResult(ctx *context.Context, elems interface{}, result interface{}) error {
 	currentUserID, _ := determineUser(ctx)
-	db := r.db
-	tx, ok := TxFromContext(ctx)
-	if ok {
-		db = tx
-	}
 
 	dbArgs := map[string]interface{}{}
+	ids := []interface{}{}
 
 	sqlStr := fmt.Sprintf(`
 	UPDATE "%s" as "currentTable" 
@@ -1027,8 +1070,12 @@ func (r *PostgresStorage) UpdateManyWithResult(ctx *context.Context, elems inter
 	indexData := 0
 	if datas.Kind() == reflect.Slice {
 		for i := 0; i < datas.Len(); i++ {
-			sqlStrIndex, arg := r.updateManyParams(currentUserID, datas.Index(i), i+1)
+			sqlStrIndex, arg, err := r.updateManyParams(currentUserID, datas.Index(i), i+1)
+			if err != nil {
+				return err
+			}
 			sqlStr += sqlStrIndex
+			ids = append(ids, r.findElemID(datas.Index(i)))
 			if indexData == 0 {
 				dbArgs = arg
 			} else {
@@ -1038,7 +1085,7 @@ func (r *PostgresStorage) UpdateManyWithResult(ctx *context.Context, elems inter
 			}
 			indexData++
 			if indexData == limit {
-				err := r.updateDataWithResult(ctx, sqlStr, dbArgs, result)
+				err := r.updateDataWithResult(ctx, sqlStr, dbArgs, ids, result)
 				if err != nil {
 					return err
 				}
@@ -1051,14 +1098,19 @@ func (r *PostgresStorage) UpdateManyWithResult(ctx *context.Context, elems inter
 				FROM (VALUES
 				`, r.tableName, r.updateManySetFields)
 				dbArgs = map[string]interface{}{}
+				ids = []interface{}{}
 			}
 		}
 	}
 
 	if datas.Kind() == reflect.Map {
 		for key, element := range datas.MapKeys() {
-			sqlStrIndex, arg := r.updateManyParams(currentUserID, datas.MapIndex(element), key+1)
+			sqlStrIndex, arg, err := r.updateManyParams(currentUserID, datas.MapIndex(element), key+1)
+			if err != nil {
+				return err
+			}
 			sqlStr += sqlStrIndex
+			ids = append(ids, r.findElemID(datas.MapIndex(element)))
 			if indexData == 0 {
 				dbArgs = arg
 			} else {
@@ -1068,7 +1120,7 @@ func (r *PostgresStorage) UpdateManyWithResult(ctx *context.Context, elems inter
 			}
 			indexData++
 			if indexData == limit {
-				err := r.updateDataWithResult(ctx, sqlStr, dbArgs, result)
+				err := r.updateDataWithResult(ctx, sqlStr, dbArgs, ids, result)
 				if err != nil {
 					return err
 				}
@@ -1081,33 +1133,15 @@ func (r *PostgresStorage) UpdateManyWithResult(ctx *context.Context, elems inter
 				FROM (VALUES
 				`, r.tableName, r.updateManySetFields)
 				dbArgs = map[string]interface{}{}
+				ids = []interface{}{}
 			}
 		}
 	}
 
-	sqlStr = strings.TrimSuffix(sqlStr, ",")
-
-	sqlStr = fmt.Sprintf(`%s
-	) as "updatedTable"("updatedAt", "updatedBy", %s)
-	where cast("currentTable".id as int) = cast("updatedTable".id as int)
-	RETURNING %s
-	`, sqlStr, r.updateManyAsFields, r.updateManySelectFields)
-
-	statement, err := db.PrepareNamed(sqlStr)
-	if err != nil {
-		return err
-	}
-	defer statement.Close()
-
-	err = statement.Select(result, dbArgs)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return r.updateDataWithResult(ctx, sqlStr, dbArgs, ids, result)
 }
 
-func (r *PostgresStorage) updateDataWithResult(ctx *context.Context, sqlStr string, dbArgs map[string]interface{}, result interface{}) error {
+func (r *PostgresStorage) updateDataWithResult(ctx *context.Context, sqlStr string, dbArgs map[string]interface{}, ids []interface{}, result interface{}) error {
 	db := r.db
 	tx, ok := TxFromContext(ctx)
 	if ok {
@@ -1122,6 +1156,11 @@ func (r *PostgresStorage) updateDataWithResult(ctx *context.Context, sqlStr stri
 	RETURNING %s
 	`, sqlStr, r.updateManyAsFields, r.updateManySelectFields)
 
+	valuesBefore, err := r.findByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
 	statement, err := db.PrepareNamed(sqlStr)
 	if err != nil {
 		return err
@@ -1133,10 +1172,27 @@ func (r *PostgresStorage) updateDataWithResult(ctx *context.Context, sqlStr stri
 		return err
 	}
 
+	err = r.decryptResult(result)
+	if err != nil {
+		return err
+	}
+
+	err = r.notifyUpdatedRows(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	// the returned rows are the after images when the result is of the storage type
+	valuesAfter, _ := r.affectedRows(result)
+	if len(valuesAfter) != len(ids) {
+		valuesAfter = nil
+	}
+	r.logUpdatedRows(ctx, ids, valuesBefore, valuesAfter)
+
 	return nil
 }
 
-func (r *PostgresStorage) updateManyParams(currentUserID int, elem interface{}, index int) (string, map[string]interface{}) {
+func (r *PostgresStorage) updateManyParams(currentUserID int, elem interface{}, index int) (string, map[string]interface{}, error) {
 	sqlStr := fmt.Sprintf(`(cast(:updatedAt%d as timestamp),%d,`, index, currentUserID)
 
 	var v reflect.Value
@@ -1194,7 +1250,23 @@ func (r *PostgresStorage) updateManyParams(currentUserID int, elem interface{},
 				}
 			}
 
-			if dbTag != "updatedAt" {
+			if encField, ok := r.encryptedField(dbTag); ok {
+				encryptedArgs := map[string]interface{}{dbTag: nil}
+				if !isNil {
+					encryptedArgs[dbTag] = val
+				}
+				err := r.encryptArgs(encryptedArgs)
+				if err != nil {
+					return "", nil, err
+				}
+
+				sqlStr += fmt.Sprintf(`cast(:%s%d as text),`, dbTag, index)
+				res[dbTag] = encryptedArgs[dbTag]
+				if encField.blindIndex != "" {
+					sqlStr += fmt.Sprintf(`cast(:%s%d as text),`, encField.blindIndex, index)
+					res[encField.blindIndex] = encryptedArgs[encField.blindIndex]
+				}
+			} else if dbTag != "updatedAt" {
 				if isNil {
 					sqlStr += fmt.Sprintf(`cast(NULL as %s),`, r.elemType.Field(i).Tag.Get("cast"))
 				} else if dbTag == "createdAt" || field.Type() == reflect.TypeOf(typeTime) {
@@ -1241,7 +1313,7 @@ func (r *PostgresStorage) updateManyParams(currentUserID int, elem interface{},
 		res = renamingKey(res, s)
 	}
 
-	return sqlStr, res
+	return sqlStr, res, nil
 }
 
 // it assumes the id column named "id"
@@ -1256,7 +1328,7 @@ func (r *PostgresStorage) findID(elem interface{}) interface{} {
 	return nil
 }
 
-func (r *PostgresStorage) updateArgs(currentUserID int, existingElem interface{}, elem interface{}) map[string]interface{} {
+func (r *PostgresStorage) updateArgs(currentUserID int, existingElem interface{}, elem interface{}) (map[string]interface{}, error) {
 	res := map[string]interface{}{
 		"updatedAt": time.Now().UTC(),
 		"updatedBy": currentUserID,
@@ -1283,7 +1355,13 @@ func (r *PostgresStorage) updateArgs(currentUserID int, existingElem interface{}
 			res[dbTag] = val
 		}
 	}
-	return res
+
+	err := r.encryptArgs(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
 }
 
 // Delete deletes the elem from database.
@@ -1297,6 +1375,11 @@ func (r *PostgresStorage) Delete(ctx *context.Context, id interface{}) error {
 		db = tx
 	}
 
+	valuesBefore, err := r.findByIDs(ctx, []interface{}{id})
+	if err != nil {
+		return err
+	}
+
 	statement, err := db.PrepareNamed(fmt.Sprintf(`
 		UPDATE "%s" SET "deletedAt" = :deletedAt, "deletedBy" = :deletedBy WHERE "id" = :id RETURNING %s
 	`, r.tableName, r.selectFields))
@@ -1310,29 +1393,29 @@ func (r *PostgresStorage) Delete(ctx *context.Context, id interface{}) error {
 		"deletedAt": time.Now().UTC(),
 		"deletedBy": currentUser,
 	}
-	now := time.Now()
-	currentUserID, currentUserType := determineUser(ctx)
 
-	err = r.createLog(ctx, &ActivityLog{
-		UserID:          currentUserID,
-		UserType:        currentUserType,
-		TableName:       r.tableName,
-		ReferenceID:     id.(int),
-		Metadata:        map[string]interface{}{},
-		ValueBefore:     nil,
-		ValueAfter:      nil,
-		TransactionTime: &now,
-		TransactionType: "Delete",
-	})
+	elems := r.newElems()
+	err = statement.Select(elems, deleteArgs)
 	if err != nil {
-		fmt.Printf("\nError while write activitylog: %v\n", err)
+		return err
 	}
 
-	_, err = statement.Exec(deleteArgs)
+	err = r.decryptResult(elems)
 	if err != nil {
 		return err
 	}
 
+	valuesAfter, ids := r.affectedRows(elems)
+	err = r.notifyChanges(ctx, notify.OperationDelete, ids)
+	if err != nil {
+		return err
+	}
+
+	err = r.createLogs(ctx, "Delete", ids, valuesBefore, valuesAfter)
+	if err != nil {
+		fmt.Printf("\nError while write activitylog: %v\n", err)
+	}
+
 	return nil
 }
 
@@ -1353,7 +1436,7 @@ func (r *PostgresStorage) DeleteMany(ctx *context.Context, ids interface{}) erro
 	}
 
 	if r.isImmutable {
-		query := fmt.Sprintf(`DELETE FROM "%s" WHERE "id" IN (:ids)`, r.tableName)
+		query := fmt.Sprintf(`DELETE FROM "%s" WHERE "id" IN (:ids) RETURNING %s`, r.tableName, r.selectFields)
 		query, args, err := sqlx.Named(query, map[string]interface{}{
 			"ids": ids,
 		})
@@ -1367,7 +1450,28 @@ func (r *PostgresStorage) DeleteMany(ctx *context.Context, ids interface{}) erro
 		}
 
 		query = db.Rebind(query)
-		db.MustExec(query, args...)
+
+		elems := r.newElems()
+		err = db.Select(elems, query, args...)
+		if err != nil {
+			return err
+		}
+
+		err = r.decryptResult(elems)
+		if err != nil {
+			return err
+		}
+
+		valuesBefore, deletedIDs := r.affectedRows(elems)
+		err = r.notifyChanges(ctx, notify.OperationHardDelete, deletedIDs)
+		if err != nil {
+			return err
+		}
+
+		err = r.createLogs(ctx, "HardDelete", deletedIDs, valuesBefore, nil)
+		if err != nil {
+			fmt.Printf("\nError while write activitylog: %v\n", err)
+		}
 		return nil
 	}
 
@@ -1376,24 +1480,49 @@ func (r *PostgresStorage) DeleteMany(ctx *context.Context, ids interface{}) erro
 		"deletedAt": time.Now().UTC(),
 	}
 
+	elemIDs := []interface{}{}
 	for i := 0; i < datas.Len(); i++ {
 		queryParam += fmt.Sprintf(":%s%d,", "id", i+1)
 		payloads[fmt.Sprintf("%s%d", "id", i+1)] = datas.Index(i).Interface()
+		elemIDs = append(elemIDs, datas.Index(i).Interface())
 	}
 
 	queryParam = strings.TrimSuffix(queryParam, ",")
 
+	valuesBefore, err := r.findByIDs(ctx, elemIDs)
+	if err != nil {
+		return err
+	}
+
 	statement, err := db.PrepareNamed(fmt.Sprintf(`
 		UPDATE "%s" SET "deletedAt" = :deletedAt WHERE "id" in (%s) RETURNING %s
 	`, r.tableName, queryParam, r.selectFields))
 	if err != nil {
 		return err
 	}
+	defer statement.Close()
+
+	elems := r.newElems()
+	err = statement.Select(elems, payloads)
+	if err != nil {
+		return err
+	}
+
+	err = r.decryptResult(elems)
+	if err != nil {
+		return err
+	}
 
-	_, err = statement.Exec(payloads)
+	valuesAfter, deletedIDs := r.affectedRows(elems)
+	err = r.notifyChanges(ctx, notify.OperationDelete, deletedIDs)
 	if err != nil {
 		return err
 	}
+
+	err = r.createLogs(ctx, "Delete", deletedIDs, valuesBefore, valuesAfter)
+	if err != nil {
+		fmt.Printf("\nError while write activitylog: %v\n", err)
+	}
 	return nil
 }
 
@@ -1432,8 +1561,8 @@ func (r *PostgresStorage) HardDelete(ctx *context.Context, id interface{}) error
 	}
 
 	statement, err := db.PrepareNamed(fmt.Sprintf(`
-		DELETE FROM "%s" WHERE "id" = :id
-	`, r.tableName))
+		DELETE FROM "%s" WHERE "id" = :id RETURNING %s
+	`, r.tableName, r.selectFields))
 	if err != nil {
 		return err
 	}
@@ -1442,11 +1571,29 @@ func (r *PostgresStorage) HardDelete(ctx *context.Context, id interface{}) error
 	deleteArgs := map[string]interface{}{
 		"id": id,
 	}
-	_, err = statement.Exec(deleteArgs)
+
+	elems := r.newElems()
+	err = statement.Select(elems, deleteArgs)
 	if err != nil {
 		return err
 	}
 
+	err = r.decryptResult(elems)
+	if err != nil {
+		return err
+	}
+
+	valuesBefore, ids := r.affectedRows(elems)
+	err = r.notifyChanges(ctx, notify.OperationHardDelete, ids)
+	if err != nil {
+		return err
+	}
+
+	err = r.createLogs(ctx, "HardDelete", ids, valuesBefore, nil)
+	if err != nil {
+		fmt.Printf("\nError while write activitylog: %v\n", err)
+	}
+
 	return nil
 }
 
@@ -1464,11 +1611,34 @@ func (r *PostgresStorage) ExecQuery(ctx *context.Context, query string, args map
 	}
 	defer statement.Close()
 
-	_, err = statement.Exec(args)
+	result, err := statement.Exec(args)
 	if err != nil {
 		return err
 	}
 
+	// the affected rows of a raw query are unknown, so it's logged once per statement
+	rowsAffected, _ := result.RowsAffected()
+	currentUserID, currentUserType := determineUser(ctx)
+	now := time.Now()
+	err = r.createLog(ctx, &ActivityLog{
+		UserID:      currentUserID,
+		UserType:    currentUserType,
+		TableName:   r.tableName,
+		ReferenceID: 0,
+		Metadata: map[string]interface{}{
+			"query":        query,
+			"args":         r.redactArgs(args),
+			"rowsAffected": rowsAffected,
+		},
+		ValueBefore:     nil,
+		ValueAfter:      nil,
+		TransactionTime: &now,
+		TransactionType: "ExecQuery",
+	})
+	if err != nil {
+		fmt.Printf("\nError while write activitylog: %v\n", err)
+	}
+
 	return nil
 }
 
@@ -1500,10 +1670,10 @@ func (r *PostgresStorage) SelectFirstWithQuery(ctx *context.Context, elems inter
 		return err
 	}
 
-	return nil
+	return r.decryptResult(elems)
 }
 
-// ActivityLog log for transactions (insert, update, delete)
+// ActivityLog log for transactions (insert, update, delete, hard delete & raw query)
 type ActivityLog struct {
 	ID              int                    `db:"id"`
 	UserID          int                    `db:"userId"`
@@ -1523,31 +1693,205 @@ func (r *PostgresStorage) createLog(ctx *context.Context, params *ActivityLog) e
 	if currentAccount == nil {
 		return nil
 	}
-	db := r.logStorage.db
+
+	r.redactLog(params)
+
+	return r.logStorage.write(ctx, &activityLogEntry{
+		owner: currentAccount,
+		log:   params,
+	})
+}
+
+// createLogs writes the activity logs of the affected rows in a single batch,
+// the before & after images of a row are matched by its id
+func (r *PostgresStorage) createLogs(ctx *context.Context, transactionType string, ids []int, valuesBefore map[int]interface{}, valuesAfter map[int]interface{}) error {
+	currentAccount := appcontext.CurrentAccount(ctx)
+	if currentAccount == nil || len(ids) == 0 {
+		return nil
+	}
+	currentUserID, currentUserType := determineUser(ctx)
+	now := time.Now()
+
+	entries := []*activityLogEntry{}
+	for _, id := range ids {
+		existingElem, isBeforeExist := valuesBefore[id]
+		elem, isAfterExist := valuesAfter[id]
+
+		var valueBefore, valueAfter map[string]interface{}
+		var err error
+		if isBeforeExist {
+			valueBefore, err = interfaceConversion(existingElem)
+			if err != nil {
+				return err
+			}
+		}
+		if isAfterExist {
+			valueAfter, err = interfaceConversion(elem)
+			if err != nil {
+				return err
+			}
+		}
+
+		metadata := map[string]interface{}{}
+		if isBeforeExist && isAfterExist {
+			metadata = r.findChanges(existingElem, elem)
+		}
+
+		activityLog := &ActivityLog{
+			UserID:          currentUserID,
+			UserType:        currentUserType,
+			TableName:       r.tableName,
+			ReferenceID:     id,
+			Metadata:        metadata,
+			ValueBefore:     valueBefore,
+			ValueAfter:      valueAfter,
+			TransactionTime: &now,
+			TransactionType: transactionType,
+		}
+		r.redactLog(activityLog)
+		entries = append(entries, &activityLogEntry{
+			owner: currentAccount,
+			log:   activityLog,
+		})
+	}
+
+	return r.logStorage.write(ctx, entries...)
+}
+
+// logUpdatedRows writes the activity logs of the updated rows,
+// the after images are collected when they aren't given
+func (r *PostgresStorage) logUpdatedRows(ctx *context.Context, ids []interface{}, valuesBefore map[int]interface{}, valuesAfter map[int]interface{}) {
+	var err error
+	if valuesAfter == nil {
+		valuesAfter, err = r.findByIDs(ctx, ids)
+	}
+	if err == nil {
+		updatedIDs := []int{}
+		for _, id := range ids {
+			updatedIDs = append(updatedIDs, referenceID(id))
+		}
+		err = r.createLogs(ctx, "Update", updatedIDs, valuesBefore, valuesAfter)
+	}
+	if err != nil {
+		fmt.Printf("\nError while write activitylog: %v\n", err)
+	}
+}
+
+// findByIDs finds the rows by its ids regardless of the owner & deletedAt,
+// it's used to collect the before & after images for the activity log
+func (r *PostgresStorage) findByIDs(ctx *context.Context, ids []interface{}) (map[int]interface{}, error) {
+	if len(ids) == 0 || appcontext.CurrentAccount(ctx) == nil {
+		return map[int]interface{}{}, nil
+	}
+
+	db := r.db
 	tx, ok := TxFromContext(ctx)
 	if ok {
 		db = tx
 	}
 
-	statement, err := db.PrepareNamed(
-		fmt.Sprintf(`
-		INSERT INTO "%s"(%s)
-		VALUES (%s)
-		`, r.logStorage.logName, r.logStorage.insertFields, r.logStorage.insertParams),
-	)
-
+	query, args, err := sqlx.In(fmt.Sprintf(`SELECT %s FROM "%s" WHERE "id" IN (?)`, r.selectFields, r.tableName), ids)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer statement.Close()
 
-	dbArgs := r.logStorage.insertArgs(currentAccount, params.UserID, params, 0)
+	query = db.Rebind(query)
 
-	_, err = statement.Exec(dbArgs)
+	elems := r.newElems()
+	err = db.Select(elems, query, args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+
+	err = r.decryptResult(elems)
+	if err != nil {
+		return nil, err
+	}
+
+	values, _ := r.affectedRows(elems)
+	return values, nil
+}
+
+// newElems creates a pointer of an empty slice of the storage type
+func (r *PostgresStorage) newElems() interface{} {
+	return reflect.New(reflect.SliceOf(r.elemType)).Interface()
+}
+
+// affectedRows maps the scanned rows of the storage type by its id
+func (r *PostgresStorage) affectedRows(elems interface{}) (map[int]interface{}, []int) {
+	values := map[int]interface{}{}
+	ids := []int{}
+
+	v := reflect.Indirect(reflect.ValueOf(elems))
+	if v.Kind() != reflect.Slice {
+		return values, ids
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		elem := elemPointer(v.Index(i))
+		if reflect.TypeOf(elem).Elem() != r.elemType {
+			continue
+		}
+		id := referenceID(r.findID(elem))
+		values[id] = elem
+		ids = append(ids, id)
+	}
+
+	return values, ids
+}
+
+// redactArgs redacts the sensitive fields of the raw query arguments
+func (r *PostgresStorage) redactArgs(args map[string]interface{}) map[string]interface{} {
+	res := map[string]interface{}{}
+	for k, v := range args {
+		res[k] = v
+	}
+	for _, field := range r.sensitiveFields {
+		if value, ok := res[field.dbKey]; ok {
+			res[field.dbKey] = r.logStorage.redact(value)
+		}
+	}
+	return res
+}
+
+// findElemID finds the id of the element given as a pointer or a reflect.Value
+func (r *PostgresStorage) findElemID(elem interface{}) interface{} {
+	if data, ok := elem.(reflect.Value); ok {
+		return r.findID(elemPointer(data))
+	}
+	return r.findID(elem)
+}
+
+// elemPointer returns the pointer of the struct value
+func elemPointer(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Interface {
+		return elemPointer(v.Elem())
+	}
+	if v.Kind() == reflect.Ptr {
+		return v.Interface()
+	}
+	if v.CanAddr() {
+		return v.Addr().Interface()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Interface()
+}
+
+func referenceID(id interface{}) int {
+	switch v := id.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case int32:
+		return int(v)
+	case *int:
+		if v != nil {
+			return *v
+		}
+	}
+	return 0
 }
 
 func getContextVariables(ctx *context.Context) (*int, int, *int) {
@@ -1589,6 +1933,20 @@ func NewLogStorage(db *sqlx.DB, logName string) *LogStorage {
 // NewPostgresStorage creates a new generic postgres Storage
 func NewPostgresStorage(db *sqlx.DB, tableName string, elem interface{}, cfg PostgresConfig, logStorage LogStorage) *PostgresStorage {
 	elemType := reflect.TypeOf(elem)
+
+	notifyChannel := ""
+	if cfg.Notify {
+		notifyChannel = cfg.NotifyChannel
+		if notifyChannel == "" {
+			notifyChannel = notify.DefaultChannel
+		}
+	}
+
+	searchLanguage := cfg.SearchLanguage
+	if searchLanguage == "" {
+		searchLanguage = defaultSearchLanguage
+	}
+
 	return &PostgresStorage{
 		db:                     db,
 		tableName:              tableName,
@@ -1601,6 +1959,11 @@ func NewPostgresStorage(db *sqlx.DB, tableName string, elem interface{}, cfg Pos
 		updateManySetFields:    updateManySetFields(elemType),
 		updateManySelectFields: updateManySelectFields(elemType),
 		updateManyAsFields:     updateManyAsFields(elemType),
+		sensitiveFields:        sensitiveFields(elemType),
+		encryptedFields:        encryptedFields(elemType),
+		keyProvider:            cfg.KeyProvider,
+		notifyChannel:          notifyChannel,
+		searchLanguage:         searchLanguage,
 		logStorage:             logStorage,
 	}
 }
@@ -1628,6 +1991,9 @@ func insertFields(elemType reflect.Type, isImmutable bool) string {
 		if !readOnlyTag(dbTag) && !emptyTag(dbTag) {
 			dbFields = append(dbFields, fmt.Sprintf("\"%s\"", dbTag))
 		}
+		if blindIndex := blindIndexColumn(field); blindIndex != "" {
+			dbFields = append(dbFields, fmt.Sprintf("\"%s\"", blindIndex))
+		}
 	}
 	return strings.Join(dbFields, ",")
 }
@@ -1643,6 +2009,9 @@ func insertParams(elemType reflect.Type, isImmutable bool, index int) string {
 		if !readOnlyTag(dbTag) && !emptyTag(dbTag) {
 			dbParams = append(dbParams, fmt.Sprintf(":%s", dbTag))
 		}
+		if blindIndex := blindIndexColumn(field); blindIndex != "" {
+			dbParams = append(dbParams, fmt.Sprintf(":%s", blindIndex))
+		}
 	}
 
 	if index != 0 {
@@ -1663,6 +2032,9 @@ func updateSetFields(elemType reflect.Type) string {
 		if !readOnlyTag(dbTag) && !emptyTag(dbTag) {
 			setFields = append(setFields, fmt.Sprintf("\"%s\" = :%s", dbTag, dbTag))
 		}
+		if blindIndex := blindIndexColumn(field); blindIndex != "" {
+			setFields = append(setFields, fmt.Sprintf("\"%s\" = :%s", blindIndex, blindIndex))
+		}
 	}
 	return strings.Join(setFields, ",")
 }
@@ -1675,6 +2047,9 @@ func updateManySetFields(elemType reflect.Type) string {
 		if !readOnlyTag(dbTag) && !emptyTag(dbTag) && dbTag != "updatedAt" {
 			setManyFields = append(setManyFields, fmt.Sprintf(`"%s" = "updatedTable"."%s"`, dbTag, dbTag))
 		}
+		if blindIndex := blindIndexColumn(field); blindIndex != "" {
+			setManyFields = append(setManyFields, fmt.Sprintf(`"%s" = "updatedTable"."%s"`, blindIndex, blindIndex))
+		}
 	}
 
 	return strings.Join(setManyFields, ",")
@@ -1700,10 +2075,40 @@ func updateManyAsFields(elemType reflect.Type) string {
 		if dbTag != "" && dbTag != "-" && dbTag != "updatedAt" {
 			dbFields = append(dbFields, fmt.Sprintf("\"%s\"", dbTag))
 		}
+		if blindIndex := blindIndexColumn(field); blindIndex != "" {
+			dbFields = append(dbFields, fmt.Sprintf("\"%s\"", blindIndex))
+		}
 	}
 	return strings.Join(dbFields, ",")
 }
 
+// kitTag checks whether the field has the option in its `kit` tag
+func kitTag(field reflect.StructField, option string) bool {
+	for _, t := range strings.Split(field.Tag.Get("kit"), ",") {
+		if strings.TrimSpace(t) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// IsProtectedField returns true for the sensitive & encrypted fields,
+// their values shouldn't leave the storage as they are
+func IsProtectedField(field reflect.StructField) bool {
+	return kitTag(field, "sensitive") || kitTag(field, "encrypted")
+}
+
+// kitTagValue returns the value of the `option=value` in the `kit` tag of the field
+func kitTagValue(field reflect.StructField, option string) string {
+	for _, t := range strings.Split(field.Tag.Get("kit"), ",") {
+		chunk := strings.SplitN(strings.TrimSpace(t), "=", 2)
+		if len(chunk) == 2 && chunk[0] == option {
+			return chunk[1]
+		}
+	}
+	return ""
+}
+
 func idTag(dbTag string) bool {
 	return dbTag == "id"
 }
@@ -0,0 +1,69 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/payfazz/commerce-kit/appcontext"
+)
+
+// Stream queries the elements according to the query & argument provided and passes them to f one by one
+// without loading the whole result, it keeps the owner & soft delete scoping of Where.
+// The elem passed to f is a new pointer of the model for every row.
+func (r *PostgresStorage) Stream(ctx *context.Context, where string, arg map[string]interface{}, f func(elem interface{}) error) error {
+	db := r.db
+	tx, ok := TxFromContext(ctx)
+	if ok {
+		db = tx
+	}
+	currentAccount := appcontext.CurrentAccount(ctx)
+
+	if !r.isImmutable {
+		where = fmt.Sprintf(`"deletedAt" IS NULL AND %s`, where)
+	}
+	if currentAccount != nil {
+		where = fmt.Sprintf(`"owner" = :currentAccount AND %s`, where)
+	}
+	arg["currentAccount"] = currentAccount
+
+	query := fmt.Sprintf(`SELECT %s FROM "%s" WHERE %s`, r.selectFields, r.tableName, where)
+	query, args, err := sqlx.Named(query, arg)
+	if err != nil {
+		return err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return err
+	}
+
+	query = db.Rebind(query)
+
+	rows, err := db.Queryx(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		elem := reflect.New(r.elemType).Interface()
+		err = rows.StructScan(elem)
+		if err != nil {
+			return err
+		}
+
+		err = r.decryptResult(elem)
+		if err != nil {
+			return err
+		}
+
+		err = f(elem)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
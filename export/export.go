@@ -0,0 +1,315 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/payfazz/commerce-kit/data"
+	"github.com/payfazz/commerce-kit/types"
+	"github.com/payfazz/commerce-kit/uploader"
+	"github.com/xuri/excelize/v2"
+)
+
+// Format represents the export file format
+type Format string
+
+// Enum value for export format
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+const defaultSheetName = "Sheet1"
+
+// Streamer streams the rows of a storage query, it's implemented by data.PostgresStorage
+type Streamer interface {
+	Stream(ctx *context.Context, where string, arg map[string]interface{}, f func(elem interface{}) error) error
+}
+
+// Options represents the options of an export.
+// Columns are the db tags of the exported fields, empty means all of the exportable fields.
+type Options struct {
+	Format    Format
+	Columns   []string
+	Where     string
+	Args      map[string]interface{}
+	SheetName string
+}
+
+// column represents an exported field with its header label
+type column struct {
+	dbTag string
+	label string
+	index int
+}
+
+// Exporter exports the rows of the storage model.
+// The header label is taken from the `export` tag, then the `json` tag, then the `db` tag,
+// and the fields tagged `export:"-"`, `kit:"sensitive"` or `kit:"encrypted"` are never exported.
+type Exporter struct {
+	storage  Streamer
+	elemType reflect.Type
+}
+
+func (e *Exporter) columns(selected []string) ([]*column, error) {
+	available := map[string]*column{}
+	all := []*column{}
+	for i := 0; i < e.elemType.NumField(); i++ {
+		field := e.elemType.Field(i)
+		dbTag := field.Tag.Get("db")
+		exportTag := field.Tag.Get("export")
+		if dbTag == "" || dbTag == "-" || exportTag == "-" || data.IsProtectedField(field) {
+			continue
+		}
+
+		label := exportTag
+		if label == "" {
+			label = strings.Split(field.Tag.Get("json"), ",")[0]
+		}
+		if label == "" || label == "-" {
+			label = dbTag
+		}
+
+		c := &column{dbTag: dbTag, label: label, index: i}
+		available[dbTag] = c
+		all = append(all, c)
+	}
+
+	if len(selected) == 0 {
+		return all, nil
+	}
+
+	columns := []*column{}
+	for _, dbTag := range selected {
+		c, ok := available[dbTag]
+		if !ok {
+			return nil, fmt.Errorf("invalid export column %s", dbTag)
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// cellValue returns the exported value of the field
+func cellValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value
+	case fmt.Stringer:
+		return value.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		valueBytes, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(valueBytes)
+	}
+	return v.Interface()
+}
+
+// csvValue returns the csv cell of the value, the text starting with a formula character is prefixed with '
+// so it isn't evaluated as a formula by the spreadsheet, the numbers are kept as is
+func csvValue(value interface{}) string {
+	switch value := value.(type) {
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case string:
+		if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			return "'" + value
+		}
+		return value
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func (e *Exporter) stream(ctx *context.Context, opts Options, columns []*column, f func(values []interface{}) error) error {
+	where := opts.Where
+	if where == "" {
+		where = `true ORDER BY "id"`
+	}
+	args := opts.Args
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+
+	return e.storage.Stream(ctx, where, args, func(elem interface{}) error {
+		v := reflect.ValueOf(elem).Elem()
+		values := make([]interface{}, len(columns))
+		for i, c := range columns {
+			values[i] = cellValue(v.Field(c.index))
+		}
+		return f(values)
+	})
+}
+
+func (e *Exporter) exportCSV(ctx *context.Context, w io.Writer, opts Options, columns []*column) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{}
+	for _, c := range columns {
+		header = append(header, c.label)
+	}
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	err = e.stream(ctx, opts, columns, func(values []interface{}) error {
+		record := make([]string, len(values))
+		for i, value := range values {
+			record[i] = csvValue(value)
+		}
+		return writer.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (e *Exporter) exportXLSX(ctx *context.Context, w io.Writer, opts Options, columns []*column) error {
+	sheetName := opts.SheetName
+	if sheetName == "" {
+		sheetName = defaultSheetName
+	}
+
+	file := excelize.NewFile()
+	defer file.Close()
+	if sheetName != defaultSheetName {
+		file.SetSheetName(defaultSheetName, sheetName)
+	}
+
+	streamWriter, err := file.NewStreamWriter(sheetName)
+	if err != nil {
+		return err
+	}
+
+	header := []interface{}{}
+	for _, c := range columns {
+		header = append(header, c.label)
+	}
+	err = streamWriter.SetRow("A1", header)
+	if err != nil {
+		return err
+	}
+
+	row := 1
+	err = e.stream(ctx, opts, columns, func(values []interface{}) error {
+		row++
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		return streamWriter.SetRow(cell, values)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = streamWriter.Flush()
+	if err != nil {
+		return err
+	}
+
+	return file.Write(w)
+}
+
+// validate returns the exported columns of the options, it fails on the invalid format or column
+func (e *Exporter) validate(opts Options) ([]*column, error) {
+	if opts.Format != CSV && opts.Format != XLSX {
+		return nil, fmt.Errorf("invalid export format %s", opts.Format)
+	}
+	return e.columns(opts.Columns)
+}
+
+// Export writes the rows of the query into the writer
+func (e *Exporter) Export(ctx *context.Context, w io.Writer, opts Options) error {
+	columns, err := e.validate(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.Format == XLSX {
+		return e.exportXLSX(ctx, w, opts, columns)
+	}
+	return e.exportCSV(ctx, w, opts, columns)
+}
+
+// ContentType returns the content type of the format
+func ContentType(format Format) string {
+	if format == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv"
+}
+
+// WriteResponse writes the export as an attachment http response,
+// the filename is given without its extension. The options are validated before the response is written
+// so the invalid format or column can still be responded as an error.
+func (e *Exporter) WriteResponse(ctx *context.Context, w http.ResponseWriter, filename string, opts Options) error {
+	_, err := e.validate(opts)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", ContentType(opts.Format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": fmt.Sprintf("%s.%s", filename, opts.Format),
+	}))
+	w.Header().Set("Content-Transfer-Encoding", "binary")
+	w.Header().Set("Expires", "0")
+	w.WriteHeader(http.StatusOK)
+
+	return e.Export(ctx, w, opts)
+}
+
+// Upload streams the export into the key of the uploader's bucket, it's meant for the large async exports
+func (e *Exporter) Upload(ctx *context.Context, service *uploader.Service, key string, opts Options) (*uploader.File, *types.Error) {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(e.Export(ctx, writer, opts))
+	}()
+
+	file, err := service.UploadStream(ctx, reader, key, ContentType(opts.Format))
+	// unblock the export when the upload stops reading early
+	reader.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		err.Path = ".Exporter->Upload()" + err.Path
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// NewExporter creates a new exporter of the storage model
+func NewExporter(storage Streamer, elem interface{}) *Exporter {
+	elemType := reflect.TypeOf(elem)
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	return &Exporter{
+		storage:  storage,
+		elemType: elemType,
+	}
+}
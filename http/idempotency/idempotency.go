@@ -0,0 +1,233 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/payfazz/commerce-kit/data"
+	"github.com/payfazz/commerce-kit/types"
+)
+
+var (
+	// ErrPayloadMismatch is returned when the idempotency key is reused with a different request
+	ErrPayloadMismatch = fmt.Errorf("idempotency key is reused with a different payload")
+	// ErrRequestInProgress is returned when the request of the idempotency key is still being processed
+	ErrRequestInProgress = fmt.Errorf("request with the same idempotency key is in progress")
+)
+
+// Enum value for idempotency key status
+const (
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+)
+
+const (
+	defaultWaitTimeout  = 30 * time.Second
+	defaultPollInterval = 200 * time.Millisecond
+	defaultLockTimeout  = 5 * time.Minute
+)
+
+// IdempotencyKey object of the idempotency key of a mutating request & its recorded response
+// swagger:model
+type IdempotencyKey struct {
+	ID                  int       `json:"id" db:"id"`
+	Key                 string    `json:"key" db:"key"`
+	Route               string    `json:"route" db:"route"`
+	RequestHash         string    `json:"requestHash" db:"requestHash"`
+	Status              string    `json:"status" db:"status"`
+	ResponseStatus      int       `json:"responseStatus" db:"responseStatus"`
+	ResponseContentType string    `json:"responseContentType" db:"responseContentType"`
+	ResponseBody        []byte    `json:"responseBody" db:"responseBody"`
+	LockedAt            time.Time `json:"lockedAt" db:"lockedAt"`
+}
+
+// IdempotencyKeyStorage represents the interface for manage IdempotencyKey object
+type IdempotencyKeyStorage interface {
+	FindByKey(ctx *context.Context, key string, route string) (*IdempotencyKey, *types.Error)
+	Insert(ctx *context.Context, idempotencyKey *IdempotencyKey) (*IdempotencyKey, *types.Error)
+	Update(ctx *context.Context, idempotencyKey *IdempotencyKey) (*IdempotencyKey, *types.Error)
+	TakeOver(ctx *context.Context, idempotencyKey *IdempotencyKey, lockedAt time.Time) (bool, *types.Error)
+	Delete(ctx *context.Context, idempotencyKey *IdempotencyKey) *types.Error
+}
+
+// ServiceInterface represents the interface for servicing IdempotencyKey object
+type ServiceInterface interface {
+	Lock(ctx *context.Context, key string, route string, requestHash string) (*IdempotencyKey, bool, *types.Error)
+	Complete(ctx *context.Context, idempotencyKey *IdempotencyKey, status int, contentType string, body []byte) *types.Error
+	Release(ctx *context.Context, idempotencyKey *IdempotencyKey) *types.Error
+}
+
+// Config represents the configuration for the idempotency service
+type Config struct {
+	WaitTimeout  time.Duration
+	PollInterval time.Duration
+	LockTimeout  time.Duration
+}
+
+// Service implements the IdempotencyKey repository service interface
+type Service struct {
+	idempotencyKeyRepository IdempotencyKeyStorage
+	config                   Config
+}
+
+func isUniqueViolation(err *types.Error) bool {
+	pqErr, ok := err.Error.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
+
+func isNotFound(err *types.Error) bool {
+	return err.Error == data.ErrNotFound
+}
+
+// Lock takes the idempotency key of the route for the request.
+// It returns true when the caller should process the request, or the completed key to be replayed.
+// A concurrent duplicate waits until the first request is completed.
+func (s *Service) Lock(ctx *context.Context, key string, route string, requestHash string) (*IdempotencyKey, bool, *types.Error) {
+	deadline := time.Now().Add(s.config.WaitTimeout)
+	for {
+		idempotencyKey, err := s.idempotencyKeyRepository.FindByKey(ctx, key, route)
+		if err != nil && !isNotFound(err) {
+			err.Path = ".IdempotencyService->Lock()" + err.Path
+			return nil, false, err
+		}
+
+		if idempotencyKey == nil {
+			idempotencyKey, err = s.idempotencyKeyRepository.Insert(ctx, &IdempotencyKey{
+				Key:         key,
+				Route:       route,
+				RequestHash: requestHash,
+				Status:      StatusProcessing,
+				LockedAt:    time.Now().UTC(),
+			})
+			if err != nil {
+				if isUniqueViolation(err) {
+					// the concurrent duplicate has taken the key first
+					continue
+				}
+				err.Path = ".IdempotencyService->Lock()" + err.Path
+				return nil, false, err
+			}
+			return idempotencyKey, true, nil
+		}
+
+		if idempotencyKey.RequestHash != requestHash {
+			return nil, false, &types.Error{
+				Path:    ".IdempotencyService->Lock()",
+				Message: ErrPayloadMismatch.Error(),
+				Error:   ErrPayloadMismatch,
+				Type:    "idempotency-error",
+			}
+		}
+
+		if idempotencyKey.Status == StatusCompleted {
+			return idempotencyKey, false, nil
+		}
+
+		if time.Since(idempotencyKey.LockedAt) > s.config.LockTimeout {
+			// the first request has been abandoned, only one of the concurrent duplicates takes it over
+			isTaken, err := s.idempotencyKeyRepository.TakeOver(ctx, idempotencyKey, time.Now().UTC())
+			if err != nil {
+				err.Path = ".IdempotencyService->Lock()" + err.Path
+				return nil, false, err
+			}
+			if isTaken {
+				return idempotencyKey, true, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, false, &types.Error{
+				Path:    ".IdempotencyService->Lock()",
+				Message: ErrRequestInProgress.Error(),
+				Error:   ErrRequestInProgress,
+				Type:    "idempotency-error",
+			}
+		}
+
+		select {
+		case <-(*ctx).Done():
+			return nil, false, &types.Error{
+				Path:    ".IdempotencyService->Lock()",
+				Message: (*ctx).Err().Error(),
+				Error:   (*ctx).Err(),
+				Type:    "golang-error",
+			}
+		case <-time.After(s.config.PollInterval):
+		}
+	}
+}
+
+// Complete records the response of the idempotency key to be replayed
+func (s *Service) Complete(ctx *context.Context, idempotencyKey *IdempotencyKey, status int, contentType string, body []byte) *types.Error {
+	idempotencyKey.Status = StatusCompleted
+	idempotencyKey.ResponseStatus = status
+	idempotencyKey.ResponseContentType = contentType
+	idempotencyKey.ResponseBody = body
+
+	_, err := s.idempotencyKeyRepository.Update(ctx, idempotencyKey)
+	if err != nil {
+		err.Path = ".IdempotencyService->Complete()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// Release removes the idempotency key so the request can be retried
+func (s *Service) Release(ctx *context.Context, idempotencyKey *IdempotencyKey) *types.Error {
+	err := s.idempotencyKeyRepository.Delete(ctx, idempotencyKey)
+	if err != nil {
+		err.Path = ".IdempotencyService->Release()" + err.Path
+		return err
+	}
+
+	return nil
+}
+
+// NewService creates a new idempotency service
+func NewService(
+	idempotencyKeyRepository IdempotencyKeyStorage,
+	cfg Config,
+) *Service {
+	if cfg.WaitTimeout <= 0 {
+		cfg.WaitTimeout = defaultWaitTimeout
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.LockTimeout <= 0 {
+		cfg.LockTimeout = defaultLockTimeout
+	}
+
+	return &Service{
+		idempotencyKeyRepository: idempotencyKeyRepository,
+		config:                   cfg,
+	}
+}
+
+// TableQuery returns the query to create the idempotency key table unique by its owner, key & route,
+// it's meant to be used in the migration
+func TableQuery(tableName string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (
+	"id" SERIAL PRIMARY KEY,
+	"owner" INT,
+	"key" VARCHAR(255) NOT NULL,
+	"route" VARCHAR(255) NOT NULL,
+	"requestHash" VARCHAR(255) NOT NULL,
+	"status" VARCHAR(32) NOT NULL,
+	"responseStatus" INT NOT NULL DEFAULT 0,
+	"responseContentType" VARCHAR(255) NOT NULL DEFAULT '',
+	"responseBody" BYTEA NOT NULL DEFAULT '',
+	"lockedAt" TIMESTAMP NOT NULL,
+	"createdAt" TIMESTAMP,
+	"createdBy" INT,
+	"updatedAt" TIMESTAMP,
+	"updatedBy" INT,
+	"deletedAt" TIMESTAMP,
+	"deletedBy" INT
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "%s_owner_key_route_idx" ON "%s" (COALESCE("owner", 0), "key", "route");`,
+		tableName, tableName, tableName)
+}
@@ -0,0 +1,133 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/payfazz/commerce-kit/http/response"
+	"github.com/payfazz/commerce-kit/notif"
+	"github.com/payfazz/commerce-kit/types"
+)
+
+const (
+	// HeaderIdempotencyKey is the request header holding the idempotency key
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed is set on the replayed responses
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// responseRecorder records the status & body written by the handler
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestHash hashes the method, url & body of the request
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// replay writes the recorded response of the idempotency key as is
+func replay(w http.ResponseWriter, idempotencyKey *IdempotencyKey) {
+	w.Header().Set(HeaderIdempotentReplayed, "true")
+	if idempotencyKey.ResponseContentType != "" {
+		w.Header().Set("Content-Type", idempotencyKey.ResponseContentType)
+	}
+	w.WriteHeader(idempotencyKey.ResponseStatus)
+	w.Write(idempotencyKey.ResponseBody)
+}
+
+// Middleware guards the mutating requests having the Idempotency-Key header,
+// the completed response is replayed for the retried request instead of processing it again
+func Middleware(service ServiceInterface, n notif.Notifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderIdempotencyKey)
+			if key == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, errRead := ioutil.ReadAll(r.Body)
+			if errRead != nil {
+				response.Error(w, n, "Bad Request", http.StatusBadRequest, types.Error{
+					Path:    ".IdempotencyMiddleware()",
+					Message: errRead.Error(),
+					Error:   errRead,
+					Type:    "golang-error",
+				})
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			route := fmt.Sprintf("%s %s", r.Method, r.URL.Path)
+			idempotencyKey, isLocked, err := service.Lock(&ctx, key, route, requestHash(r, body))
+			if err != nil {
+				err.Path = ".IdempotencyMiddleware()" + err.Path
+				switch err.Error {
+				case ErrPayloadMismatch:
+					response.Error(w, n, err.Message, http.StatusUnprocessableEntity, *err)
+				case ErrRequestInProgress:
+					response.Error(w, n, err.Message, http.StatusConflict, *err)
+				default:
+					response.Error(w, n, "Internal Server Error", http.StatusInternalServerError, *err)
+				}
+				return
+			}
+
+			if !isLocked {
+				replay(w, idempotencyKey)
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					service.Release(&ctx, idempotencyKey)
+					panic(recovered)
+				}
+
+				// the server errors aren't recorded so the request can be retried
+				if recorder.status == 0 || recorder.status >= http.StatusInternalServerError {
+					err = service.Release(&ctx, idempotencyKey)
+				} else {
+					err = service.Complete(&ctx, idempotencyKey, recorder.status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+				}
+				if err != nil {
+					fmt.Printf("\n[Commerce-Kit - IdempotencyMiddleware] Error: %v\n", err.Message)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
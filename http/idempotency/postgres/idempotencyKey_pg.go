@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/payfazz/commerce-kit/appcontext"
+	"github.com/payfazz/commerce-kit/data"
+	"github.com/payfazz/commerce-kit/http/idempotency"
+	"github.com/payfazz/commerce-kit/types"
+)
+
+// IdempotencyKeyPostgresStorage implements the idempotency key repository service interface
+type IdempotencyKeyPostgresStorage struct {
+	repository data.GenericStorage
+	tableName  string
+}
+
+// FindByKey get idempotency key by its key & route of the current account,
+// the owner is matched as the unique index does so the key without the current account is found too
+func (s *IdempotencyKeyPostgresStorage) FindByKey(ctx *context.Context, key string, route string) (*idempotency.IdempotencyKey, *types.Error) {
+	owner := 0
+	currentAccount := appcontext.CurrentAccount(ctx)
+	if currentAccount != nil {
+		owner = *currentAccount
+	}
+
+	var idempotencyKey idempotency.IdempotencyKey
+	err := s.repository.Single(ctx, &idempotencyKey, `COALESCE("owner", 0) = :owner AND "key" = :key AND "route" = :route`, map[string]interface{}{
+		"owner": owner,
+		"key":   key,
+		"route": route,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".IdempotencyKeyPostgresStorage->FindByKey()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return &idempotencyKey, nil
+}
+
+// Insert create a new idempotency key
+func (s *IdempotencyKeyPostgresStorage) Insert(ctx *context.Context, idempotencyKey *idempotency.IdempotencyKey) (*idempotency.IdempotencyKey, *types.Error) {
+	err := s.repository.Insert(ctx, idempotencyKey)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".IdempotencyKeyPostgresStorage->Insert()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return idempotencyKey, nil
+}
+
+// Update updates an idempotency key
+func (s *IdempotencyKeyPostgresStorage) Update(ctx *context.Context, idempotencyKey *idempotency.IdempotencyKey) (*idempotency.IdempotencyKey, *types.Error) {
+	err := s.repository.Update(ctx, idempotencyKey)
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".IdempotencyKeyPostgresStorage->Update()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return idempotencyKey, nil
+}
+
+// TakeOver locks the abandoned idempotency key again,
+// it returns false when the key has been taken over or completed by the concurrent request
+func (s *IdempotencyKeyPostgresStorage) TakeOver(ctx *context.Context, idempotencyKey *idempotency.IdempotencyKey, lockedAt time.Time) (bool, *types.Error) {
+	ids := []int{}
+	err := s.repository.SelectWithQuery(ctx, &ids, fmt.Sprintf(`UPDATE "%s" SET "lockedAt" = :now, "updatedAt" = :now
+		WHERE "id" = :id AND "status" = :status AND "lockedAt" = :oldLockedAt
+		RETURNING "id"`, s.tableName), map[string]interface{}{
+		"now":         lockedAt,
+		"id":          idempotencyKey.ID,
+		"status":      idempotency.StatusProcessing,
+		"oldLockedAt": idempotencyKey.LockedAt,
+	})
+	if err != nil {
+		return false, &types.Error{
+			Path:    ".IdempotencyKeyPostgresStorage->TakeOver()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	idempotencyKey.LockedAt = lockedAt
+	return true, nil
+}
+
+// Delete removes an idempotency key, it's hard deleted so the key can be used again
+func (s *IdempotencyKeyPostgresStorage) Delete(ctx *context.Context, idempotencyKey *idempotency.IdempotencyKey) *types.Error {
+	err := s.repository.HardDelete(ctx, idempotencyKey.ID)
+	if err != nil {
+		return &types.Error{
+			Path:    ".IdempotencyKeyPostgresStorage->Delete()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "pq-error",
+		}
+	}
+
+	return nil
+}
+
+// NewIdempotencyKeyPostgresStorage creates new idempotency key repository of the table created by idempotency.TableQuery
+func NewIdempotencyKeyPostgresStorage(
+	repository data.GenericStorage,
+	tableName string,
+) *IdempotencyKeyPostgresStorage {
+	return &IdempotencyKeyPostgresStorage{
+		repository: repository,
+		tableName:  tableName,
+	}
+}
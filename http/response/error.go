@@ -50,6 +50,8 @@ func Error(w http.ResponseWriter, n notif.Notifier, data string, status int, err
 		errorCode = "BadRequestError"
 	case http.StatusUnprocessableEntity:
 		errorCode = "UnprocessableEntityError"
+	case http.StatusConflict:
+		errorCode = "ConflictError"
 	case http.StatusInternalServerError:
 		errorCode = "InternalServerError"
 	case http.StatusNotImplemented:
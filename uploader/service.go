@@ -9,7 +9,10 @@ import (
 	"image"
 	"image/jpeg"
 	_ "image/png"
+	"io"
+	"mime"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -21,6 +24,7 @@ import (
 	"github.com/nfnt/resize"
 
 	"github.com/payfazz/commerce-kit/appcontext"
+	"github.com/payfazz/commerce-kit/client"
 	"github.com/payfazz/commerce-kit/logperform"
 	"github.com/payfazz/commerce-kit/types"
 
@@ -247,6 +251,82 @@ func (s *Service) Upload(ctx *context.Context, fileBytes []byte, fileName string
 	}, nil
 }
 
+// UploadStream uploads the content of the reader as it is into the key of the bucket,
+// the upload is aborted when the reader fails so the partial content isn't stored
+func (s *Service) UploadStream(ctx *context.Context, reader io.Reader, key string, contentType string) (*File, *types.Error) {
+	writerCtx, cancel := context.WithCancel(*ctx)
+	defer cancel()
+
+	bw, err := s.bucket.NewWriter(writerCtx, key, &blob.WriterOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return nil, &types.Error{
+			Path:    ".UploaderService->UploadStream()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "golang-error",
+		}
+	}
+
+	_, err = io.Copy(bw, reader)
+	if err != nil {
+		// the writer is closed after its context is cancelled to abort the write
+		cancel()
+		bw.Close()
+		return nil, &types.Error{
+			Path:    ".UploaderService->UploadStream()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "golang-error",
+		}
+	}
+
+	if err = bw.Close(); err != nil {
+		return nil, &types.Error{
+			Path:    ".UploaderService->UploadStream()",
+			Message: err.Error(),
+			Error:   err,
+			Type:    "golang-error",
+		}
+	}
+
+	return &File{
+		URL: fmt.Sprintf("%s/%s/%s", s.url, s.bucketName, key),
+	}, nil
+}
+
+// Archive uploads the content of the reader into the key of the bucket, it implements data.LogArchiver
+func (s *Service) Archive(ctx *context.Context, key string, reader io.Reader) error {
+	_, err := s.UploadStream(ctx, reader, key, mime.TypeByExtension(filepath.Ext(key)))
+	if err != nil {
+		return err.Error
+	}
+	return nil
+}
+
+// UploadDownload streams the response body of the GET path of the client into the key of the bucket,
+// the content type of the response is kept and nothing is stored when the download fails
+func (s *Service) UploadDownload(ctx *context.Context, httpClient *client.HTTPClient, path string, key string, opts ...client.CallOption) (*File, *types.Error) {
+	res, errDo := httpClient.Stream(ctx, client.GET, path, opts...)
+	if errDo != nil {
+		return nil, &types.Error{
+			Path:    ".UploaderService->UploadDownload()",
+			Message: errDo.Message,
+			Error:   errDo.Error,
+			Type:    "golang-error",
+		}
+	}
+	defer res.Body.Close()
+
+	file, errUpload := s.UploadStream(ctx, res.Body, key, res.Header.Get("Content-Type"))
+	if errUpload != nil {
+		errUpload.Path = ".UploaderService->UploadDownload()" + errUpload.Path
+		return nil, errUpload
+	}
+	return file, nil
+}
+
 // NewService creates new uploader service
 func NewService(bucket *blob.Bucket, bucketName string, url string) *Service {
 	return &Service{